@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/adobe/ferry/copier"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var sourceCluster, destCluster string
+var copyDirectories []string
+var copyPrefixes []string
+var copyBegin, copyEnd string
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy",
+	Short: "❌ Copy all (or filtered) keys and values from one cluster to another",
+	Long: `Copy data from a source FoundationDB cluster straight into a destination
+cluster, without going through export files. Ranges are read and written in
+parallel from this host only (ferry serve nodes are not used), so this host's
+network and CPU bound the copy. Like export, this is not a point-in-time snapshot`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		if sourceCluster == "" || destCluster == "" {
+			gLogger.Fatal("Both --source-cluster and --dest-cluster are required")
+		}
+		source, err := fdb.OpenDatabase(sourceCluster)
+		if err != nil {
+			gLogger.Fatal("Error opening source cluster", zap.String("cluster-file", sourceCluster), zap.Error(err))
+		}
+		dest, err := fdb.OpenDatabase(destCluster)
+		if err != nil {
+			gLogger.Fatal("Error opening destination cluster", zap.String("cluster-file", destCluster), zap.Error(err))
+		}
+
+		cp, err := copier.NewCopier(source, dest,
+			copier.Logger(gLogger),
+			copier.Dryrun(viper.GetBool("dryrun")),
+			copier.Threads(viper.GetInt("threads")),
+			copier.SplitSize(viper.GetInt64("split-bytes")),
+			copier.MaxRetries(viper.GetInt("max-retries")),
+			copier.BatchSize(viper.GetInt("batch-bytes"), viper.GetInt("batch-keys")),
+		)
+		if err != nil {
+			gLogger.Fatal("Error initializing copier", zap.Error(err))
+		}
+		scope, err := keyScope(source, copyDirectories, copyPrefixes, copyBegin, copyEnd)
+		if err != nil {
+			gLogger.Fatal("Error resolving copy scope", zap.Error(err))
+		}
+		err = cp.Copy(scope)
+		if err != nil {
+			gLogger.Fatal("Error copying", zap.Error(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+
+	// ------------------------------------------------------------------------
+	// PLEASE DO NOT SET ANY "DEFAULTS" for CLI arguments. Set them instead as
+	// viper.SetDefault() in root.go. Then it will apply to both paths. If you
+	// set them here, it will always override what is in .ferry.yaml (making the
+	// config file useless)
+	// ------------------------------------------------------------------------
+	copyCmd.Flags().BoolP("dryrun", "n", false, "Print the ranges that would be copied")
+	copyCmd.Flags().IntP("threads", "t", 0, "How many ranges to copy at once")
+	copyCmd.Flags().Int64P("split-bytes", "", 0, "Copy shards larger than this many bytes as several ranges in parallel, e.g. 250000000 (0 = don't split)")
+	copyCmd.Flags().IntP("max-retries", "", 0, "How many more times to copy ranges that failed (default 2, 0 = don't retry)")
+	copyCmd.Flags().IntP("batch-bytes", "", 0, "Max bytes (keys+values) per write transaction (default 1000000, max 9000000)")
+	copyCmd.Flags().IntP("batch-keys", "", 0, "Max keys per write transaction (default 10000)")
+	copyCmd.Flags().StringVarP(&sourceCluster, "source-cluster", "", "", "Cluster file of the cluster to copy from")
+	copyCmd.Flags().StringVarP(&destCluster, "dest-cluster", "", "", "Cluster file of the cluster to copy to")
+	copyCmd.Flags().StringSliceVarP(&copyDirectories, "directory", "", nil, "Copy only this directory (a/b/c) and its subdirectories. Repeatable")
+	copyCmd.Flags().StringSliceVarP(&copyPrefixes, "prefix", "", nil, "Copy only keys with this prefix (\\xNN escapes allowed). Repeatable")
+	copyCmd.Flags().StringVarP(&copyBegin, "begin", "", "", "Copy only keys >= this key (\\xNN escapes allowed)")
+	copyCmd.Flags().StringVarP(&copyEnd, "end", "", "", "Copy only keys < this key (default \\xFF)")
+}
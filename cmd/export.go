@@ -13,13 +13,25 @@ governing permissions and limitations under the License.
 package cmd
 
 import (
+	"os"
+	"strings"
+
 	"github.com/adobe/ferry/exporter/client"
 	"github.com/adobe/ferry/finder"
+	"github.com/adobe/ferry/format"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
+var resumeJobID string
+var planOnly bool
+var exportDirectories []string
+var exportPrefixes []string
+var exportBegin, exportEnd string
+
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export",
@@ -27,13 +39,23 @@ var exportCmd = &cobra.Command{
 	Long: `This utility will export all (or filtered) data from FoundationDB 
 to one of the possible stores - a local file-system folder, Azure blobstore or Amazon S3
 Export is not done in a single transaction and that implies you should only do this
-if your data is static or you don't care for it being a point-in-time snapshot`,
+if your data is static or you don't care for it being a point-in-time snapshot,
+or use --lock to keep everyone else out of the database while it is exported`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		finder, err := finder.NewFinder(gFDB, finder.Logger(gLogger))
+		fdbFinder, err := finder.NewFinder(gFDB, finder.Logger(gLogger))
 		if err != nil {
 			gLogger.Fatal("Error initializing finder", zap.Error(err))
 		}
+		_, err = format.ParseCodec(viper.GetString("compress"))
+		if err != nil {
+			gLogger.Fatal("Invalid --compress", zap.Error(err))
+		}
+		var redactRules []client.RedactRule
+		err = viper.UnmarshalKey("redact.rules", &redactRules)
+		if err != nil {
+			gLogger.Fatal("Invalid redact.rules in config", zap.Error(err))
+		}
 
 		exp, err := client.NewExporter(gFDB,
 			storeURL, viper.GetInt("port"),
@@ -41,27 +63,66 @@ if your data is static or you don't care for it being a point-in-time snapshot`,
 			client.Logger(gLogger),
 			client.Dryrun(viper.GetBool("dryrun")),
 			client.Sample(viper.GetInt("read-percent")),
+			client.SampleBy(viper.GetString("sample-by"), viper.GetString("sample-seed")),
 			client.ExportFormat(viper.GetString("export-format")),
-			client.Compress(viper.GetBool("compress")),
+			client.Compress(viper.GetString("compress")),
 			client.ReaderThreads(viper.GetInt("threads")),
 			client.Collect(viper.GetString("collect")),
+			client.Resume(resumeJobID),
+			client.Scope(exportDirectories, exportPrefixes, exportBegin, exportEnd),
+			client.MaxRetries(viper.GetInt("max-retries")),
+			client.SplitSize(viper.GetInt64("split-bytes")),
+			client.MaxFileSize(viper.GetInt64("max-file-size")),
+			client.Lock(viper.GetBool("lock")),
+			client.RateLimit(viper.GetInt64("max-bytes-per-sec"), viper.GetInt64("max-keys-per-sec")),
+			client.GlobalRateLimit(viper.GetInt64("global-max-bytes-per-sec"), viper.GetInt64("global-max-keys-per-sec")),
+			client.Priority(viper.GetBool("batch-priority"), viper.GetString("transaction-tag")),
+			client.Backoff(viper.GetBool("backoff")),
+			client.Encrypt(viper.GetBool("encrypt"), encryptionKey()),
+			client.Redact(redactRules),
 		)
 		if err != nil {
 			gLogger.Fatal("Error initializing exporter", zap.Error(err))
 		}
-		bKeys, err := finder.GetBoundaryKeys()
+		scope, err := exportScope()
 		if err != nil {
-			gLogger.Fatal("Error fetching boundary keys", zap.Error(err))
+			gLogger.Fatal("Error resolving export scope", zap.Error(err))
 		}
-		partitionMap, err := finder.GetLocations(bKeys, false)
+		var partitionMap *finder.PartitionMap
+		if len(scope) == 0 {
+			bKeys, err := fdbFinder.GetBoundaryKeys()
+			if err != nil {
+				gLogger.Fatal("Error fetching boundary keys", zap.Error(err))
+			}
+			partitionMap, err = fdbFinder.GetLocations(bKeys, false)
+			if err != nil {
+				gLogger.Fatal("Error fetching locations", zap.Error(err))
+			}
+		} else {
+			partitionMap, err = fdbFinder.GetLocationsInRanges(scope, false)
+			if err != nil {
+				gLogger.Fatal("Error fetching locations", zap.Error(err))
+			}
+			gLogger.Info("Export scope",
+				zap.Int("key-ranges", len(scope)),
+				zap.Int("shards", len(partitionMap.Ranges)))
+		}
+		partitionMap, err = exp.ExcludeCompleted(partitionMap)
 		if err != nil {
-			gLogger.Fatal("Error fetching locations", zap.Error(err))
+			gLogger.Fatal("Error loading job journal", zap.Error(err))
 		}
 
 		exportPlan, err := exp.AssignSources(partitionMap)
 		if err != nil {
 			gLogger.Fatal("Error assigning export nodes", zap.Error(err))
 		}
+		if planOnly {
+			err = exp.WritePlan(os.Stdout, exportPlan)
+			if err != nil {
+				gLogger.Fatal("Error printing export plan", zap.Error(err))
+			}
+			return
+		}
 
 		err = exp.ScheduleFetch(exportPlan)
 		if err != nil {
@@ -70,6 +131,51 @@ if your data is static or you don't care for it being a point-in-time snapshot`,
 	},
 }
 
+// exportScope turns --directory, --prefix and --begin/--end into key
+// ranges. No scope (nil) means the whole keyspace.
+func exportScope() (scope []fdb.KeyRange, err error) {
+	return keyScope(gFDB, exportDirectories, exportPrefixes, exportBegin, exportEnd)
+}
+
+// keyScope resolves directories (in db), printable prefixes and a
+// printable begin/end pair into merged key ranges.
+func keyScope(db fdb.Database, directories, prefixes []string, beginKey, endKey string) (scope []fdb.KeyRange, err error) {
+
+	for _, dir := range directories {
+		kranges, err := finder.DirectoryRanges(db, strings.Split(strings.Trim(dir, "/"), "/"))
+		if err != nil {
+			return nil, err
+		}
+		scope = append(scope, kranges...)
+	}
+	for _, p := range prefixes {
+		prefix, err := finder.ParsePrintable(p)
+		if err != nil {
+			return nil, err
+		}
+		krange, err := fdb.PrefixRange(prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid prefix %s", p)
+		}
+		scope = append(scope, krange)
+	}
+	if beginKey != "" || endKey != "" {
+		begin, err := finder.ParsePrintable(beginKey)
+		if err != nil {
+			return nil, err
+		}
+		end := fdb.Key("\xFF")
+		if endKey != "" {
+			end, err = finder.ParsePrintable(endKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+		scope = append(scope, fdb.KeyRange{Begin: begin, End: end})
+	}
+	return finder.MergeRanges(scope), nil
+}
+
 func init() {
 	rootCmd.AddCommand(exportCmd)
 
@@ -81,9 +187,30 @@ func init() {
 	// ------------------------------------------------------------------------
 	exportCmd.Flags().BoolP("dryrun", "n", false, "Dryrun connectivity check")
 	exportCmd.Flags().IntP("read-percent", "r", 100, "Read all (100%) or sample, say 10%")
-	exportCmd.Flags().StringP("export-format", "f", "archive", "archive|keys")
-	exportCmd.Flags().BoolP("compress", "c", false, "Compress export files (.lz4)")
+	exportCmd.Flags().StringP("sample-by", "", "", "How --read-percent picks keys: random (default, differs every run), key (hash of the key) or tuple:N (hash of its first N tuple elements)")
+	exportCmd.Flags().StringP("sample-seed", "", "", "Seed for --sample-by key|tuple:N. Same seed, same sample")
+	exportCmd.Flags().StringP("export-format", "f", "archive", "archive|keys|keys-printable|keys-base64|keys-tuple|jsonl|csv|parquet")
+	exportCmd.Flags().StringP("compress", "c", "", "Compress export files: none|lz4|zstd[:1-22]|gzip[:1-9] (-c alone is lz4)")
+	exportCmd.Flags().Lookup("compress").NoOptDefVal = format.CODEC_LZ4
 	exportCmd.Flags().IntP("threads", "t", 0, "How many threads per range")
 	exportCmd.Flags().StringP("collect", "", "", "Bring exported files to this host at this directory. Only applies to file:// targets")
+	exportCmd.Flags().IntP("max-retries", "", 0, "How many other replicas to try a failed range on (default 2, 0 = don't fail over)")
+	exportCmd.Flags().Int64P("split-bytes", "", 0, "Export shards estimated over this many bytes as several ranges of about this size, in parallel, e.g. 250000000 (0 = don't split)")
+	exportCmd.Flags().Int64P("max-file-size", "", 0, "Start a new file once the current one has this many bytes (as stored), recording each file's key range and uploading each one once full (0 = one file per range)")
+	exportCmd.Flags().BoolP("lock", "", false, "Lock the database for the whole export (as fdbcli lock does), so it is a point-in-time snapshot. Other clients can't read or write until it ends")
+	exportCmd.Flags().Int64P("max-bytes-per-sec", "", 0, "Read at most this many bytes per second on each node (0 = no limit)")
+	exportCmd.Flags().Int64P("max-keys-per-sec", "", 0, "Read at most this many keys per second on each node (0 = no limit)")
+	exportCmd.Flags().Int64P("global-max-bytes-per-sec", "", 0, "Read at most this many bytes per second across all nodes (0 = no limit)")
+	exportCmd.Flags().Int64P("global-max-keys-per-sec", "", 0, "Read at most this many keys per second across all nodes (0 = no limit)")
+	exportCmd.Flags().BoolP("batch-priority", "", false, "Read at batch priority, so the cluster throttles the export before other work")
+	exportCmd.Flags().StringP("transaction-tag", "", "", "Tag read transactions with this, so ratekeeper can throttle them")
+	exportCmd.Flags().BoolP("backoff", "", false, "Slow down while the cluster reports ratekeeper limiting or long storage queues")
+	exportCmd.Flags().BoolP("encrypt", "", false, "Encrypt export files with the key configured on each node (see encryption in .ferry.yaml)")
 	exportCmd.Flags().StringVarP(&storeURL, "store-url", "s", "/tmp/", "Source/target for export/import/manage")
+	exportCmd.Flags().StringVarP(&resumeJobID, "resume", "", "", "Resume an interrupted export job (job-id is logged at start)")
+	exportCmd.Flags().BoolVarP(&planOnly, "plan-only", "", false, "Print which host would export which ranges (with estimated bytes) as JSON, and exit")
+	exportCmd.Flags().StringSliceVarP(&exportDirectories, "directory", "", nil, "Export only this directory (a/b/c) and its subdirectories. Repeatable")
+	exportCmd.Flags().StringSliceVarP(&exportPrefixes, "prefix", "", nil, "Export only keys with this prefix (\\xNN escapes allowed). Repeatable")
+	exportCmd.Flags().StringVarP(&exportBegin, "begin", "", "", "Export only keys >= this key (\\xNN escapes allowed)")
+	exportCmd.Flags().StringVarP(&exportEnd, "end", "", "", "Export only keys < this key (default \\xFF)")
 }
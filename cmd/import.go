@@ -19,6 +19,10 @@ import (
 	"go.uber.org/zap"
 )
 
+var manifestFile string
+var remapPrefixes []string
+var remapDirectories []string
+
 // importCmd represents the import command
 var importCmd = &cobra.Command{
 	Use:   "import",
@@ -34,24 +38,28 @@ Import all data or a subset of keys to a target FoundationDB instance
 			client.Dryrun(viper.GetBool("dryrun")),
 			client.Sample(viper.GetBool("sample")),
 			client.WriterThreads(viper.GetInt("threads")),
+			client.ManifestFile(manifestFile),
+			client.BatchSize(viper.GetInt("batch-bytes"), viper.GetInt("batch-keys")),
+			client.OnConflict(viper.GetString("on-conflict")),
+			client.Remap(remapPrefixes, remapDirectories),
 		)
 		if err != nil {
-			gLogger.Fatal("Error initializing exporter", zap.Error(err))
+			gLogger.Fatal("Error initializing importer", zap.Error(err))
 		}
 		importPlan, err := exp.AssignTargets()
 		if err != nil {
-			gLogger.Fatal("Error assigning export nodes", zap.Error(err))
+			gLogger.Fatal("Error assigning import nodes", zap.Error(err))
 		}
 
 		err = exp.ScheduleImport(importPlan)
 		if err != nil {
-			gLogger.Fatal("Error scheduling exports", zap.Error(err))
+			gLogger.Fatal("Error scheduling imports", zap.Error(err))
 		}
 	},
 }
 
 func init() {
-	//rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(importCmd)
 
 	// ------------------------------------------------------------------------
 	// PLEASE DO NOT SET ANY "DEFAULTS" for CLI arguments. Set them instead as
@@ -59,5 +67,13 @@ func init() {
 	// set them here, it will always override what is in .ferry.yaml (making the
 	// config file useless)
 	// ------------------------------------------------------------------------
+	importCmd.Flags().BoolP("dryrun", "n", false, "Dryrun connectivity check")
+	importCmd.Flags().IntP("threads", "t", 0, "How many writer threads per node")
+	importCmd.Flags().IntP("batch-bytes", "", 0, "Max bytes (keys+values) per write transaction (default 1000000, max 9000000)")
+	importCmd.Flags().IntP("batch-keys", "", 0, "Max keys per write transaction (default 10000)")
+	importCmd.Flags().StringP("on-conflict", "", "", "When a key exists: overwrite|skip-existing|fail-on-conflict|clear-first (default overwrite)")
 	importCmd.Flags().StringVarP(&storeURL, "store-url", "s", "/tmp/", "Source/target for export/import/manage")
+	importCmd.Flags().StringSliceVarP(&remapPrefixes, "remap-prefix", "", nil, "Rewrite keys starting with old to start with new: old=new (\\xNN escapes allowed). Repeatable")
+	importCmd.Flags().StringSliceVarP(&remapDirectories, "remap-directory", "", nil, "Move a directory (and subdirectories) to another path in the target: a/b=c/d. Repeatable")
+	importCmd.Flags().StringVarP(&manifestFile, "manifest", "", "", "Manifest file in store-url to import from (default: MANIFEST.json)")
 }
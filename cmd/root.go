@@ -13,10 +13,12 @@ governing permissions and limitations under the License.
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/adobe/ferry/format"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -165,6 +167,7 @@ func initConfig() {
 	}
 	viper.SetDefault("port", 8001)
 	viper.SetDefault("threads", 10)
+	viper.SetDefault("max-retries", 2)
 
 	viper.AutomaticEnv() // read in environment variables that match
 
@@ -196,7 +199,9 @@ func initConfig() {
 	}
 
 	// FLAGS SPECIFIC TO EXPORT
-	for _, v := range []string{"dryrun", "read-percent", "export-format", "compress", "threads", "collect"} {
+	for _, v := range []string{"dryrun", "read-percent", "sample-by", "sample-seed", "export-format", "compress", "threads", "collect", "max-retries", "split-bytes",
+		"max-file-size", "lock", "max-bytes-per-sec", "max-keys-per-sec", "global-max-bytes-per-sec", "global-max-keys-per-sec",
+		"batch-priority", "transaction-tag", "backoff", "encrypt"} {
 		if pf := exportCmd.Flags().Lookup(v); pf != nil {
 			err := viper.BindPFlag(v, pf)
 			if err != nil {
@@ -210,6 +215,31 @@ func initConfig() {
 			os.Exit(1)
 		}
 	}
+	// FLAGS SPECIFIC TO IMPORT AND COPY
+	// dryrun, threads, split-bytes and max-retries are also export flags. Bind them to
+	// these commands' flags only when one of them is being run.
+	for cmd, flags := range map[*cobra.Command][]string{
+		importCmd: {"dryrun", "threads", "batch-bytes", "batch-keys", "on-conflict"},
+		copyCmd:   {"dryrun", "threads", "split-bytes", "max-retries", "batch-bytes", "batch-keys"},
+	} {
+		if cmd.CalledAs() == "" {
+			continue
+		}
+		for _, v := range flags {
+			if pf := cmd.Flags().Lookup(v); pf != nil {
+				err := viper.BindPFlag(v, pf)
+				if err != nil {
+					// CAN'T USE ZAP - Logger not initilized yet
+					fmt.Printf("Error from BindPFlag (%s): %+v\n", cmd.Name(), err)
+					os.Exit(1)
+				}
+			} else {
+				// CAN'T USE ZAP - Logger not initilized yet
+				fmt.Println("Unknown flag ", v)
+				os.Exit(1)
+			}
+		}
+	}
 	/*
 		// FLAGS SPECIFIC TO STATS COMMAND
 		for _, v := range []string{"threads"} {
@@ -271,3 +301,32 @@ func initConfig() {
 
 	initFDB()
 }
+
+// redactSalt is this host's secret for redaction rules that hash or
+// tokenize values (redact.salt, or redact.salt_file), nil if none.
+func redactSalt() []byte {
+	if file := viper.GetString("redact.salt_file"); file != "" {
+		salt, err := os.ReadFile(file)
+		if err != nil {
+			gLogger.Fatal("Error reading redaction salt", zap.Error(err))
+		}
+		return bytes.TrimSpace(salt)
+	}
+	if salt := viper.GetString("redact.salt"); salt != "" {
+		return []byte(salt)
+	}
+	return nil
+}
+
+// encryptionKey is this host's key material from .ferry.yaml
+// (encryption.key_file or encryption.master_key, and optionally
+// encryption.key_id), or nil if there is none.
+func encryptionKey() *format.Key {
+	key, err := format.LoadKey(viper.GetString("encryption.key_file"),
+		viper.GetString("encryption.master_key"),
+		viper.GetString("encryption.key_id"))
+	if err != nil {
+		gLogger.Fatal("Error loading encryption key", zap.Error(err))
+	}
+	return key
+}
@@ -30,6 +30,8 @@ var serveCmd = &cobra.Command{
 			viper.GetInt("port"),
 			viper.GetString("tls_ferry.cert"),
 			viper.GetString("tls_ferry.privKey"),
+			encryptionKey(),
+			redactSalt(),
 			gLogger)
 		err := srv.ServeImportExport()
 		if err != nil {
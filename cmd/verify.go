@@ -12,11 +12,8 @@ governing permissions and limitations under the License.
 package cmd
 
 import (
-	"encoding/binary"
-	"io"
-
-	"github.com/adobe/blackhole/lib/archive"
-	"github.com/pkg/errors"
+	"github.com/adobe/ferry/format"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -32,93 +29,43 @@ var verifyCmd = &cobra.Command{
 
 	Run: func(cmd *cobra.Command, args []string) {
 
-		ar, err := archive.OpenArchive(fileName, 4_000_000)
+		if fileName == "" {
+			gLogger.Fatal("No file to verify, set --file")
+		}
+		records, err := format.Open(fileName, 4_000_000, format.Decrypt(encryptionKey()))
 		if err != nil {
-			gLogger.Fatal("Error",
-				zap.Error(errors.Wrapf(
-					err, "Unable to open export file %s", fileName)))
+			gLogger.Fatal("Error", zap.Error(err))
 		}
+		defer records.Close()
 
-		for {
-			key, value, err := readRecord(ar)
-			if err == io.EOF {
-				gLogger.Info("End of file")
-				break
-			}
-			if err != nil {
-				gLogger.Error("Verification error",
-					zap.Error(err))
-				break
-			}
+		header := records.Header()
+		gLogger.Info("Archive",
+			zap.String("file", fileName),
+			zap.Int("format-version", header.Version),
+			zap.String("begin", fdb.Printable(header.Begin)),
+			zap.String("end", fdb.Printable(header.End)),
+			zap.Int64("read-version", header.ReadVersion))
+
+		for records.Next() {
 			if viper.GetBool("verbose") {
-				gLogger.Info("KV", zap.ByteString("key", key),
-					zap.ByteString("value", value))
+				gLogger.Info("KV", zap.ByteString("key", records.Key()),
+					zap.ByteString("value", records.Value()))
 			}
 		}
-	},
-}
-
-const MAX_KEY_LEN = (1 << 14) - 1   // Max 14 bits for its length. We only need 10k. Buffer till 16k
-const MAX_VALUE_LEN = (1 << 18) - 1 // Max 18 bits for its length. We only need 100k. Buffer till 260k
-
-func readRecord(ar archive.Archive) (key, value []byte, err error) {
-	const UINT32LEN = 4
-	var lbuf = make([]byte, UINT32LEN)
-
-	n, err := io.ReadFull(ar, lbuf)
-	if err != nil {
-		if err == io.EOF {
-			return nil, nil, err // io.EOF - Not an error
+		if err := records.Err(); err != nil {
+			// Checksum, authentication (encrypted files) or format error
+			gLogger.Fatal("Verification failed",
+				zap.String("file", fileName),
+				zap.Int64("records", records.Count()),
+				zap.Error(err))
 		}
-		return nil, nil, errors.Wrapf(err, "[1] Error reading archive file")
-	}
-	if n != len(lbuf) {
-		gLogger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(lbuf)),
-		)
-		return nil, nil, errors.New("[1] Unexpected EOF for archive file")
-	}
-	recordLen := binary.LittleEndian.Uint32(lbuf)
-	keyLen := recordLen >> 18
-	valueLen := recordLen & MAX_KEY_LEN
-
-	if keyLen > 1000 {
-		gLogger.Error("Long key",
-			zap.Uint32("key", keyLen),
-			zap.Uint32("valueLen", valueLen),
-		)
-		return key, value, nil
-	}
-	key = make([]byte, keyLen)
-	value = make([]byte, valueLen)
-	n, err = io.ReadFull(ar, key)
-	if err != nil {
-		return nil, nil, errors.Wrapf(err, "[2] Error reading archive file")
-	}
-	if n != len(key) {
-		gLogger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(key)),
-		)
-		return nil, nil, errors.New("[2] Unexpected EOF for archive file")
-	}
-	n, err = io.ReadFull(ar, value)
-	if err != nil {
-		return nil, nil, errors.Wrapf(err, "[3] Error reading archive file")
-	}
-	if n != len(value) {
-		gLogger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(value)),
-		)
-		return nil, nil, errors.New("[3] Unexpected EOF for archive file")
-	}
-	return key, value, nil
+		gLogger.Info("End of file", zap.Int64("records", records.Count()),
+			zap.String("range-end", fdb.Printable(records.End())))
+	},
 }
 
 func init() {
-	//rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
 
 	// ------------------------------------------------------------------------
 	// PLEASE DO NOT SET ANY "DEFAULTS" for CLI arguments. Set them instead as
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package copier copies key ranges from one cluster straight into another,
+// without landing files anywhere. It runs in a single process: one export
+// session reading the source and one import session writing the destination.
+// Unlike export and import it doesn't use `ferry serve` nodes, so it's bound
+// by the one host it runs on. Within that, every range (or piece of one, see
+// SplitSize) is read and written by its own reader/writer pair, Threads at
+// a time.
+package copier
+
+import (
+	"context"
+
+	exporter "github.com/adobe/ferry/exporter/session"
+	"github.com/adobe/ferry/finder"
+	importer "github.com/adobe/ferry/importer/session"
+	"github.com/adobe/ferry/progress"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type CopierOption func(cp *Copier)
+
+type Copier struct {
+	// Must have properties
+	source fdb.Database
+	dest   fdb.Database
+
+	// Optional, but defaults if not set
+	logger *zap.Logger
+
+	// Optional, set via CopierOptions
+	dryRun     bool
+	threads    int
+	batchBytes int
+	batchKeys  int
+	splitBytes int64
+	maxRetries int
+
+	progress *progress.Progress
+}
+
+func NewCopier(source, dest fdb.Database, opts ...CopierOption) (cp *Copier, err error) {
+
+	cp = &Copier{
+		source: source,
+		dest:   dest,
+	}
+	for _, opt := range opts {
+		opt(cp)
+	}
+	// if logger is not set, we must set one
+	if cp.logger == nil {
+		cp.logger, err = zap.NewProduction()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Logger not supplied. Can't initialize one either")
+		}
+	}
+	return cp, nil
+}
+
+func Logger(logger *zap.Logger) CopierOption {
+	return func(cp *Copier) {
+		cp.logger = logger
+	}
+}
+
+func Dryrun(dryRun bool) CopierOption {
+	return func(cp *Copier) {
+		cp.dryRun = dryRun
+	}
+}
+
+// Threads is how many ranges are copied at once
+func Threads(threads int) CopierOption {
+	return func(cp *Copier) {
+		cp.threads = threads
+	}
+}
+
+// SplitSize cuts shards into pieces of about `bytes` at FDB's split
+// points, so a large shard is copied by several threads at once.
+// 0 copies every shard whole.
+func SplitSize(bytes int64) CopierOption {
+	return func(cp *Copier) {
+		cp.splitBytes = bytes
+	}
+}
+
+// MaxRetries is how many more times ranges that failed are copied.
+// Writes overwrite what is there, so copying a range again is harmless.
+func MaxRetries(maxRetries int) CopierOption {
+	return func(cp *Copier) {
+		cp.maxRetries = maxRetries
+	}
+}
+
+// BatchSize bounds each write transaction on the destination.
+// See importer/session for the defaults.
+func BatchSize(bytes, keys int) CopierOption {
+	return func(cp *Copier) {
+		cp.batchBytes = bytes
+		cp.batchKeys = keys
+	}
+}
+
+// Copy copies `scope` (everything if empty) from source to dest. Work is
+// split along the source's shard boundaries, found with finder.GetLocations.
+// Ranges that fail are copied again, up to MaxRetries more rounds.
+func (cp *Copier) Copy(scope []fdb.KeyRange) (err error) {
+
+	if len(scope) == 0 {
+		scope = []fdb.KeyRange{{Begin: fdb.Key(""), End: fdb.Key("\xff")}}
+	}
+	fdbFinder, err := finder.NewFinder(cp.source, finder.Logger(cp.logger))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to initialize finder")
+	}
+	pmap, err := fdbFinder.GetLocationsInRanges(scope, true)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to find shards in source cluster")
+	}
+	shards := len(pmap.Ranges)
+	pmap = cp.splitLarge(pmap)
+	cp.logger.Info("Copy plan",
+		zap.Int("key-ranges", len(scope)),
+		zap.Int("shards", shards),
+		zap.Int("ranges", len(pmap.Ranges)),
+		zap.Int("threads", cp.threads))
+	if cp.dryRun {
+		for _, rl := range pmap.Ranges {
+			cp.logger.Info("DRYRUN",
+				zap.String("begin", fdb.Printable(rl.Krange.Begin.FDBKey())),
+				zap.String("end", fdb.Printable(rl.Krange.End.FDBKey())))
+		}
+		return nil
+	}
+
+	dest, err := importer.NewSession(cp.dest, "", 1, cp.logger, false,
+		cp.batchBytes, cp.batchKeys, importer.ON_CONFLICT_OVERWRITE, nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to start writing to destination cluster")
+	}
+	defer dest.Finalize()
+
+	cp.progress = progress.New("Copy", "ranges", len(pmap.Ranges))
+	stop := cp.progress.Report(cp.logger)
+	defer stop()
+
+	var kranges []fdb.KeyRange
+	for _, rl := range pmap.Ranges {
+		kranges = append(kranges, rl.Krange)
+	}
+	var failed []exporter.RangeResult
+	for round := 0; ; round++ {
+		failed, err = cp.copyRound(dest, kranges)
+		if err != nil {
+			return err
+		}
+		if len(failed) == 0 || round >= cp.maxRetries {
+			break
+		}
+		kranges = nil
+		for _, r := range failed {
+			kranges = append(kranges, r.KeyRange)
+		}
+		cp.logger.Info("Retrying failed ranges",
+			zap.Int("round", round+1),
+			zap.Int("ranges", len(kranges)))
+	}
+
+	for _, r := range failed {
+		cp.logger.Error("Range could not be copied",
+			zap.String("begin", fdb.Printable(r.KeyRange.Begin.FDBKey())),
+			zap.String("end", fdb.Printable(r.KeyRange.End.FDBKey())),
+			zap.Int("attempts", cp.maxRetries+1),
+			zap.Error(r.Err))
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("Copy incomplete (%d of %d ranges failed)", len(failed), len(pmap.Ranges))
+	}
+	cp.logger.Info("Copy done", zap.Int("ranges", len(pmap.Ranges)))
+	return nil
+}
+
+// copyRound copies kranges with a session of its own, as a finalized
+// session takes no more ranges, and returns the results of those that failed.
+func (cp *Copier) copyRound(dest *importer.ImporterSession, kranges []fdb.KeyRange) (failed []exporter.RangeResult, err error) {
+
+	es, err := exporter.NewCopySession(cp.source, dest, cp.threads, cp.logger)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to start reading from source cluster")
+	}
+
+	watched := make(chan error, 1)
+	go func() {
+		watched <- es.Watch(context.Background(), func(r exporter.RangeResult) error {
+			cp.progress.Add(r.Proto())
+			return nil
+		})
+	}()
+	for _, krange := range kranges {
+		es.Send(krange)
+	}
+	es.Finalize()
+	<-watched // has seen every result once the session is finalized
+
+	for _, r := range es.RangeResults() {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed, nil
+}
+
+// splitLarge cuts every shard over cp.splitBytes into pieces of about that size
+func (cp *Copier) splitLarge(pmap *finder.PartitionMap) *finder.PartitionMap {
+
+	if cp.splitBytes <= 0 {
+		return pmap
+	}
+	split := &finder.PartitionMap{}
+	for _, x := range pmap.Ranges {
+		pieces, err := finder.SplitRange(cp.source, x.Krange, cp.splitBytes)
+		if err != nil {
+			cp.logger.Warn("Unable to split range, copying it whole", zap.Error(err))
+			pieces = []fdb.KeyRange{x.Krange}
+		}
+		for _, piece := range pieces {
+			split.Ranges = append(split.Ranges, finder.RangeLocation{Krange: piece, Hosts: x.Hosts})
+		}
+	}
+	return split
+}
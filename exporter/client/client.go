@@ -13,8 +13,14 @@ governing permissions and limitations under the License.
 package client
 
 import (
+	"github.com/adobe/ferry/exporter/session"
+	"github.com/adobe/ferry/format"
+	"github.com/adobe/ferry/journal"
+	"github.com/adobe/ferry/manifest"
+	"github.com/adobe/ferry/progress"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -34,10 +40,51 @@ type ExporterClient struct {
 	// Optional, set via ExporterOptions
 	dryRun        bool
 	readPercent   int
-	compress      bool
+	sampleBy      string
+	sampleSeed    string
+	compression   string
+	encrypt       bool
+	key           *format.Key // this host's, to check collected files. nil if none
 	readerThreads int
 	collectDir    string
 	exportFormat  string
+	jobID         string
+	resume        bool
+	scope         journal.Scope // as given, a resumed job must have the same
+	maxRetries    int
+	splitBytes    int64
+	maxFileSize   int64
+	lock          bool
+
+	// Value masking, as configured and as sent to the nodes
+	redactRules []RedactRule
+	redact      []*ferry.RedactRule
+	redacted    []manifest.RedactRule // for the manifest
+
+	// Read limits per node and for the whole job, 0 is unlimited
+	nodeBytesPerSec   int64
+	nodeKeysPerSec    int64
+	globalBytesPerSec int64
+	globalKeysPerSec  int64
+	batchPriority     bool
+	transactionTag    string
+	backoff           bool
+	hosts             int // taking part in the current round, to share global limits
+
+	conns map[string]ferry.FerryClient
+	// All replicas of each planned range, to fail over to
+	replicas map[rangeKey][]string
+	// Estimated size of each planned range, nil if unknown
+	sizes map[rangeKey]int64
+
+	journal *journal.Journal
+	// Files finished by an earlier run of a resumed job
+	resumedFiles []*ferry.FinalizedFile
+	// Filled in as each host finishes, saved once all are done
+	manifest *manifest.Manifest
+	// Per-range results as they come in, from all hosts
+	progress  *progress.Progress
+	transient transientRanges
 }
 
 // exportGroup is a dynamic data derived from []storageGroup
@@ -47,7 +94,8 @@ type ExporterClient struct {
 type exportGroup struct {
 	kranges []fdb.KeyRange
 	host    string
-	conn    ferry.FerryClient // Not exclusive to this
+	bytes   int64             // estimated, 0 if unknown
+	conn    ferry.FerryClient // Not exclusive to this, set when scheduled
 }
 
 func NewExporter(db fdb.Database,
@@ -66,6 +114,17 @@ func NewExporter(db fdb.Database,
 	for _, opt := range opts {
 		opt(exp)
 	}
+	err = session.ValidSampleBy(exp.sampleBy)
+	if err != nil {
+		return nil, err
+	}
+	if exp.lock && exp.resume {
+		return nil, errors.New("A locked export can't resume a job, the files of the earlier run are not as of the lock")
+	}
+	err = exp.resolveRedaction()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to resolve redaction rules")
+	}
 	// if logger is not set, we must set one
 	if exp.logger == nil {
 		exp.logger, err = zap.NewProduction()
@@ -73,9 +132,21 @@ func NewExporter(db fdb.Database,
 			return nil, errors.Wrapf(err, "Logger not supplied. Can't initialize one either")
 		}
 	}
+	if exp.jobID == "" {
+		jobID, err := uuid.NewRandom()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create a job ID")
+		}
+		exp.jobID = jobID.String()
+	}
+	exp.journal = journal.New(db, exp.jobID)
 	return exp, nil
 }
 
+func (exp *ExporterClient) GetJobID() string {
+	return exp.jobID
+}
+
 func Logger(logger *zap.Logger) ExporterOption {
 	return func(exp *ExporterClient) {
 		exp.logger = logger
@@ -88,9 +159,14 @@ func Dryrun(dryRun bool) ExporterOption {
 	}
 }
 
-func Compress(compress bool) ExporterOption {
+// Compress export files with codec (and level), as understood
+// by format.ParseCodec: none|lz4|zstd[:level]|gzip[:level]
+func Compress(codec string) ExporterOption {
 	return func(exp *ExporterClient) {
-		exp.compress = compress
+		exp.compression = codec
+		if c, err := format.ParseCodec(codec); err == nil {
+			exp.compression = c.String() // as recorded, for --resume
+		}
 	}
 }
 
@@ -112,8 +188,128 @@ func Sample(sample int) ExporterOption {
 	}
 }
 
+// SampleBy picks the keys a --read-percent export keeps by a hash of
+// each key (session.SAMPLE_BY_KEY) or of its first N tuple elements
+// ("tuple:N"), so runs with the same seed keep the same keys. The
+// default, session.SAMPLE_RANDOM, is a new sample every run.
+func SampleBy(by, seed string) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.sampleBy = by
+		exp.sampleSeed = seed
+	}
+}
+
 func ExportFormat(format string) ExporterOption {
 	return func(exp *ExporterClient) {
 		exp.exportFormat = format
 	}
 }
+
+// Resume continues an interrupted export job instead of starting a new one.
+func Resume(jobID string) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.jobID = jobID
+		exp.resume = jobID != ""
+	}
+}
+
+// Scope records what the export is limited to (--directory, --prefix,
+// --begin/--end), so a job can't be resumed with a different one.
+func Scope(directories, prefixes []string, begin, end string) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.scope = journal.Scope{Directories: directories, Prefixes: prefixes, Begin: begin, End: end}
+	}
+}
+
+// MaxRetries is how many other replicas a failed range is tried on.
+func MaxRetries(maxRetries int) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.maxRetries = maxRetries
+	}
+}
+
+// SplitSize makes ranges estimated over `bytes` be exported as several
+// smaller ranges (files). 0 turns splitting off.
+func SplitSize(bytes int64) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.splitBytes = bytes
+	}
+}
+
+// MaxFileSize has nodes start a new file once the one they are writing
+// reaches `bytes` (as stored, after compression), so a range can end up
+// in several files. 0 is one file per range.
+func MaxFileSize(bytes int64) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.maxFileSize = bytes
+	}
+}
+
+// Lock has the database locked while it is exported, so all files are
+// as of one version. Nothing but lock-aware transactions (the nodes'
+// reads) gets through until the export ends.
+func Lock(lock bool) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.lock = lock
+	}
+}
+
+// RateLimit caps how fast each node reads (bytes and keys per second).
+// 0 is unlimited.
+func RateLimit(bytesPerSec, keysPerSec int64) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.nodeBytesPerSec = bytesPerSec
+		exp.nodeKeysPerSec = keysPerSec
+	}
+}
+
+// GlobalRateLimit caps how fast all nodes together read. Every node
+// taking part gets an equal share. 0 is unlimited.
+func GlobalRateLimit(bytesPerSec, keysPerSec int64) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.globalBytesPerSec = bytesPerSec
+		exp.globalKeysPerSec = keysPerSec
+	}
+}
+
+// Priority runs reads at batch priority and/or tags them,
+// so ratekeeper can throttle the export before other work.
+func Priority(batch bool, tag string) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.batchPriority = batch
+		exp.transactionTag = tag
+	}
+}
+
+// Backoff makes nodes slow down while the cluster status
+// reports ratekeeper limiting or long storage queues.
+func Backoff(backoff bool) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.backoff = backoff
+	}
+}
+
+// Encrypt has every node encrypt its files with the key configured
+// on it. key is this host's copy, if any: it is only used to check
+// files brought back by --collect, and never sent anywhere.
+func Encrypt(encrypt bool, key *format.Key) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.encrypt = encrypt
+		exp.key = key
+	}
+}
+
+// nodeLimit is the lower of a per-node limit and a node's
+// share of a global one. 0 means neither is set.
+func (exp *ExporterClient) nodeLimit(node, global int64) int64 {
+	if global > 0 && exp.hosts > 0 {
+		share := global / int64(exp.hosts)
+		if share < 1 {
+			share = 1
+		}
+		if node <= 0 || share < node {
+			return share
+		}
+	}
+	return node
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import "testing"
+
+func TestNodeLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		hosts        int
+		node, global int64
+		want         int64
+	}{
+		{"no limits", 4, 0, 0, 0},
+		{"per node only", 4, 100, 0, 100},
+		{"global shared", 4, 0, 1000, 250},
+		{"node limit lower", 4, 100, 1000, 100},
+		{"share lower", 4, 500, 1000, 250},
+		{"at least 1 per node", 4, 0, 3, 1},
+		{"no hosts yet", 0, 100, 1000, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &ExporterClient{hosts: tt.hosts}
+			if got := exp.nodeLimit(tt.node, tt.global); got != tt.want {
+				t.Errorf("nodeLimit(%d, %d) with %d hosts = %d, want %d",
+					tt.node, tt.global, tt.hosts, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,55 +18,167 @@ import (
 	"io"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/adobe/ferry/fdbstat"
+	"github.com/adobe/ferry/format"
+	"github.com/adobe/ferry/journal"
+	"github.com/adobe/ferry/manifest"
+	"github.com/adobe/ferry/progress"
 	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
-func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err error) {
+// ScheduleFetchByNode exports ranges from eg.host, taking them from sched
+// as the host's reader threads free up (see scheduler). `failed` lists the
+// ranges the host took but did not confirm as exported - with the rest of
+// its own queue if the session broke off.
+func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, sched *scheduler, dryRun bool) (failed []fdb.KeyRange, err error) {
+
+	// everything this host was going to do, for when it can't
+	drain := func() []fdb.KeyRange {
+		for krange, ok := sched.nextOwn(eg.host); ok; krange, ok = sched.nextOwn(eg.host) {
+			eg.kranges = append(eg.kranges, krange)
+		}
+		return eg.kranges
+	}
+	planned := len(eg.kranges)
+	eg.kranges = nil // from here on: the ranges this host took
 
 	exp.logger.Info("Starting session to",
-		zap.Int("ranges", len(eg.kranges)),
+		zap.Int("ranges", planned),
 		zap.String("host", eg.host))
 	resp, err := eg.conn.StartExportSession(context.Background(), &ferry.Target{
-		TargetUrl:     exp.targetURL,
-		ReadPercent:   int32(exp.readPercent),
-		ExportFormat:  exp.exportFormat,
-		ReaderThreads: int32(exp.readerThreads),
-		Compress:      exp.compress,
+		TargetUrl:      exp.targetURL,
+		ReadPercent:    int32(exp.readPercent),
+		SampleBy:       exp.sampleBy,
+		SampleSeed:     exp.sampleSeed,
+		Redact:         exp.redact,
+		ExportFormat:   exp.exportFormat,
+		ReaderThreads:  int32(exp.readerThreads),
+		Compression:    exp.compression,
+		Encrypt:        exp.encrypt,
+		JobId:          exp.jobID,
+		MaxBytesPerSec: exp.nodeLimit(exp.nodeBytesPerSec, exp.globalBytesPerSec),
+		MaxKeysPerSec:  exp.nodeLimit(exp.nodeKeysPerSec, exp.globalKeysPerSec),
+		BatchPriority:  exp.batchPriority,
+		TransactionTag: exp.transactionTag,
+		Backoff:        exp.backoff,
+		MaxFileSize:    exp.maxFileSize,
+		LockAware:      exp.lock,
+		Host:           eg.host,
 	})
 	if err != nil {
-		return errors.Wrapf(err, "Unable to initiate session with peer")
+		return drain(), errors.Wrapf(err, "Unable to initiate session with peer")
 	}
 	sessionID := resp.SessionId
 
+	finished := make(chan *ferry.KeyRangeResponse)
+	close(finished)
+	stopWatch := func() {}
+	// Ranges the host reported as exported, and their files. Those are
+	// journaled already, so they are not retried if the session breaks off.
+	exported := map[rangeKey]bool{}
+	var exportedFiles []*ferry.FinalizedFile
+	note := func(r *ferry.KeyRangeResponse) {
+		if r.Status == ferry.KeyRangeResponse_SUCCESS {
+			exported[newRangeKey(fdb.KeyRange{Begin: fdb.Key(r.BeginKey), End: fdb.Key(r.EndKey)})] = true
+			exportedFiles = append(exportedFiles, r.Files...)
+		}
+	}
+	// abort ends the watch, and gives back the ranges this host took or
+	// was still to take that it did not report as exported.
+	abort := func(err error) ([]fdb.KeyRange, error) {
+		stopWatch()
+		for r := range finished {
+			note(r)
+		}
+		if exp.manifest != nil {
+			exp.manifest.AddFiles(eg.host, exportedFiles)
+		}
+		var unexported []fdb.KeyRange
+		for _, krange := range drain() {
+			if !exported[newRangeKey(krange)] {
+				unexported = append(unexported, krange)
+			}
+		}
+		return unexported, err
+	}
 	if !dryRun {
+		// As many ranges in flight as the host has reader threads
+		inFlightMax := exp.readerThreads
+		if inFlightMax < 1 {
+			inFlightMax = 1
+		}
+		var ctx context.Context
+		ctx, stopWatch = context.WithCancel(context.Background())
+		defer stopWatch()
+		finished = exp.watchSession(ctx, eg, sessionID, inFlightMax)
 		exportClient, err := eg.conn.Export(context.Background())
 		if err != nil {
-			return errors.Wrapf(err, "Unable to initiate export session with peer")
+			return abort(errors.Wrapf(err, "Unable to initiate export session with peer"))
 		}
-
-		for _, krange := range eg.kranges {
-			err = exportClient.Send(&ferry.KeyRequest{
+		send := func(krange fdb.KeyRange) error {
+			eg.kranges = append(eg.kranges, krange)
+			return exportClient.Send(&ferry.KeyRequest{
 				Begin:     krange.Begin.FDBKey(),
 				End:       krange.End.FDBKey(),
 				SessionId: sessionID,
 			})
-			if err != nil {
-				return errors.Wrapf(err, "Unable to send key via export client")
+		}
+
+		inFlight := 0
+		watching := true
+		for {
+			for ; inFlight < inFlightMax; inFlight++ {
+				krange, ok := sched.next(eg.host)
+				if !ok {
+					break
+				}
+				err = send(krange)
+				if err != nil {
+					return abort(errors.Wrapf(err, "Unable to send key via export client"))
+				}
+			}
+			if inFlight == 0 {
+				break
 			}
+			var r *ferry.KeyRangeResponse
+			r, watching = <-finished
+			if watching {
+				note(r)
+			} else {
+				// Can't tell when ranges finish. Queue up all of our own
+				// ranges, the server works through them at its own pace.
+				for krange, ok := sched.nextOwn(eg.host); ok; krange, ok = sched.nextOwn(eg.host) {
+					err = send(krange)
+					if err != nil {
+						return abort(errors.Wrapf(err, "Unable to send key via export client"))
+					}
+				}
+				break
+			}
+			inFlight--
 		}
 		resp, err = exportClient.CloseAndRecv()
 		if err != nil && err != io.EOF {
-			return errors.Wrapf(err, "Unable to flush queue on export client")
+			return abort(errors.Wrapf(err, "Unable to flush queue on export client"))
 		}
 		exp.logger.Info(fmt.Sprintf("%+v", resp))
+		if planned != len(eg.kranges) {
+			exp.logger.Info("Ranges taken over",
+				zap.String("host", eg.host),
+				zap.Int("planned", planned),
+				zap.Int("exported", len(eg.kranges)))
+		}
 
 	} else {
+		drain()
 		exp.logger.Info("DRYRUN",
 			zap.Int("ranges", len(eg.kranges)),
 			zap.String("host", eg.host))
@@ -79,17 +191,20 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 	resp, err = eg.conn.StopExportSession(context.Background(),
 		&ferry.Session{SessionId: sessionID})
 	if err != nil {
-		return errors.Wrapf(err, "Error from StopSession")
+		return abort(errors.Wrapf(err, "Error from StopSession"))
+	}
+	for range finished {
+		// the session is stopped, so the watch ends once it has seen every range
 	}
 	exp.logger.Info("Export saved", zap.Int("files", len(resp.FinalizedFiles)))
-	err = saveArchiveSummary(eg.host, resp.FinalizedFiles)
-	if err != nil {
-		return errors.Wrapf(err, "Error from saveArchiveSummary")
+	if !dryRun {
+		failed = exp.unconfirmedRanges(eg, resp.Ranges)
+	}
+	if exp.manifest != nil {
+		exp.manifest.AddFiles(eg.host, resp.FinalizedFiles)
 	}
 
-	if exp.collectDir != "" && // --collect /foo/bar argument exists
-		(!strings.Contains(exp.targetURL, "://") || // and it is a raw-path (not a s3:// type URL)
-			strings.HasPrefix(exp.targetURL, "file://")) { // OR it is a file:// URL
+	if exp.collecting() {
 
 		exp.logger.Info("Bringing files from each node",
 			zap.String("dest", exp.collectDir))
@@ -108,12 +223,12 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 						FileName:  finalFile.FileName,
 					}) // Max 1 MB chunk. GRPC hard limit is 4 MB
 				if err != nil {
-					return errors.Wrapf(err, "Error from EndSession")
+					return failed, errors.Wrapf(err, "Error from EndSession")
 				}
 				localPath := path.Join(exp.collectDir, finalFile.FileName)
 				fp, err := os.OpenFile(localPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
 				if err != nil {
-					return errors.Wrapf(err, "Create of local file failed: %s", localPath)
+					return failed, errors.Wrapf(err, "Create of local file failed: %s", localPath)
 				}
 				for {
 					block, err := gc.Recv()
@@ -121,17 +236,17 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 						break
 					}
 					if err != nil {
-						return errors.Wrapf(err, "Recv on block of file %s failed", finalFile)
+						return failed, errors.Wrapf(err, "Recv on block of file %s failed", finalFile)
 					}
 					fileSize += int64(len(block.BlockData))
 					n, err := fp.Write(block.BlockData)
 					if err != nil || n != len(block.BlockData) {
-						return errors.Wrapf(err, "Write on block of file %s failed", localPath)
+						return failed, errors.Wrapf(err, "Write on block of file %s failed", localPath)
 					}
 				}
 				err = fp.Close()
 				if err != nil {
-					return errors.Wrapf(err, "Write on block of file %s failed", localPath)
+					return failed, errors.Wrapf(err, "Write on block of file %s failed", localPath)
 				}
 				exp.logger.Info("Downloaded",
 					zap.String("file", finalFile.FileName),
@@ -139,6 +254,14 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 					zap.String("local-path", localPath),
 					zap.Duration("duration", time.Since(st)),
 				)
+				if exp.key != nil && finalFile.KeyId == exp.key.ID {
+					// Files stay encrypted here too. Make sure they decrypt
+					// before the node's copy is gone.
+					err = decrypts(localPath, exp.key)
+					if err != nil {
+						return failed, errors.Wrapf(err, "Collected file %s does not decrypt", localPath)
+					}
+				}
 				_, err = eg.conn.RemoveExportedFile(context.Background(),
 					&ferry.FileRequest{
 						SessionId: sessionID,
@@ -146,7 +269,7 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 						FileName:  finalFile.FileName,
 					})
 				if err != nil {
-					return errors.Wrapf(err, "Delete of source file %s failed", finalFile)
+					return failed, errors.Wrapf(err, "Delete of source file %s failed", finalFile)
 				}
 			}
 		}
@@ -155,39 +278,283 @@ func (exp *ExporterClient) ScheduleFetchByNode(eg exportGroup, dryRun bool) (err
 	_, err = eg.conn.EndExportSession(context.Background(),
 		&ferry.Session{SessionId: sessionID})
 	if err != nil {
-		return errors.Wrapf(err, "Error from EndSession")
+		return failed, errors.Wrapf(err, "Error from EndSession")
 	}
 
-	return nil
+	return failed, nil
 }
-func saveArchiveSummary(host string, finalizedFiles []*ferry.FinalizedFile) (err error) {
-	fileName := fmt.Sprintf("%s.out", host)
-	fp, err := os.Create(fileName)
+
+// unconfirmedRanges returns the ranges sent to the host that it did not
+// report back as SUCCESS (including ones it did not report on at all).
+func (exp *ExporterClient) unconfirmedRanges(eg exportGroup, ranges []*ferry.KeyRangeResponse) (failed []fdb.KeyRange) {
+
+	succeeded := map[rangeKey]bool{}
+	for _, r := range ranges {
+		krange := fdb.KeyRange{Begin: fdb.Key(r.BeginKey), End: fdb.Key(r.EndKey)}
+		if r.Status == ferry.KeyRangeResponse_SUCCESS {
+			succeeded[newRangeKey(krange)] = true
+		} else {
+			exp.transient.mark(krange, r.Status == ferry.KeyRangeResponse_TRANSIENT)
+			exp.logger.Warn("Range failed",
+				zap.String("host", eg.host),
+				zap.String("begin", fdb.Printable(r.BeginKey)),
+				zap.String("end", fdb.Printable(r.EndKey)),
+				zap.String("status", r.Status.String()),
+				zap.String("error", r.ErrorDetails))
+		}
+	}
+	for _, krange := range eg.kranges {
+		if !succeeded[newRangeKey(krange)] {
+			failed = append(failed, krange)
+		}
+	}
+	return failed
+}
+func (exp *ExporterClient) ScheduleFetch(exportPlan map[string]exportGroup) (err error) {
+
+	var unlock func() // nil unless the database is locked
+	if !exp.dryRun {
+		clusterDescription, err := fdbstat.GetClusterDescription(exp.db)
+		if err != nil {
+			exp.logger.Warn("Unable to read cluster description", zap.Error(err))
+		}
+		exp.manifest = manifest.New(exp.jobID, clusterDescription)
+		exp.manifest.AddFiles("", exp.resumedFiles)
+		if exp.readPercent < 100 {
+			exp.manifest.Sample = &manifest.Sample{
+				ReadPercent: exp.readPercent,
+				By:          exp.sampleBy,
+				Seed:        exp.sampleSeed,
+			}
+		}
+		exp.manifest.Redaction = exp.redacted
+		srvy, err := fdbstat.NewSurveyor(exp.db, fdbstat.Logger(exp.logger))
+		if err == nil {
+			var dirs fdbstat.DirListing
+			dirs, err = srvy.GetAllDirectories()
+			if err == nil {
+				exp.manifest.Directories = manifestDirectories(dirs)
+			}
+		}
+		if err != nil {
+			// Only needed to remap directories on import
+			exp.logger.Warn("Unable to list directories for the manifest", zap.Error(err))
+		}
+
+		if !exp.resume {
+			err = exp.journal.Start(journal.JobInfo{
+				TargetURL:    exp.targetURL,
+				ExportFormat: exp.exportFormat,
+				Compression:  exp.compression,
+				Encrypted:    exp.encrypt,
+				ReadPercent:  exp.readPercent,
+				SampleBy:     exp.sampleBy,
+				SampleSeed:   exp.sampleSeed,
+				Redaction:    exp.redactionSummary(),
+				Scope:        exp.scope,
+				StartTime:    exp.manifest.StartTime,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Unable to start job journal")
+			}
+		}
+		exp.logger.Info("Export job", zap.String("job-id", exp.jobID), zap.Bool("resume", exp.resume))
+
+		if exp.lock {
+			lock, err := lockDatabase(exp.db)
+			if err != nil {
+				return err
+			}
+			unlock = exp.holdLock(lock)
+			defer unlock()
+			exp.manifest.LockVersion = lock.version
+			exp.logger.Info("Database locked",
+				zap.String("lock-uid", lock.String()),
+				zap.Int64("lock-version", lock.version))
+		}
+	}
+
+	planned := 0
+	for _, eg := range exportPlan {
+		planned += len(eg.kranges)
+	}
+	exp.progress = progress.New("Export", "ranges", planned)
+	if !exp.dryRun {
+		exp.estimateSize(exportPlan)
+		stop := exp.progress.Report(exp.logger)
+		defer stop()
+	}
+
+	var unexported []fdb.KeyRange
+	var hardErr error
+	tried := map[rangeKey][]string{}
+	for round := 0; len(exportPlan) > 0; round++ {
+		failed, err := exp.fetchRound(exportPlan)
+		if err != nil {
+			hardErr = err
+		}
+		if exp.dryRun || len(failed) == 0 {
+			break
+		}
+		var exhausted []fdb.KeyRange
+		exportPlan, exhausted, err = exp.ReassignFailed(failed, tried)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to reschedule failed ranges")
+		}
+		unexported = append(unexported, exhausted...)
+		exp.logger.Info("Retrying failed ranges",
+			zap.Int("round", round+1),
+			zap.Int("hosts", len(exportPlan)),
+			zap.Int("given-up", len(exhausted)))
+	}
+	if unlock != nil {
+		// Nothing is read past this point
+		unlock()
+	}
+
+	if exp.dryRun {
+		return hardErr
+	}
+	if len(unexported) > 0 {
+		for _, krange := range unexported {
+			rk := newRangeKey(krange)
+			exp.logger.Error("Range could not be exported",
+				zap.String("begin", fdb.Printable(krange.Begin.FDBKey())),
+				zap.String("end", fdb.Printable(krange.End.FDBKey())),
+				zap.Strings("tried", tried[rk]))
+		}
+		exp.logger.Error("Export incomplete. Finished ranges are journaled, re-run with --resume to export only the rest",
+			zap.String("job-id", exp.jobID),
+			zap.Int("ranges-failed", len(unexported)))
+		return errors.Errorf("Export job %s incomplete (%d ranges failed)", exp.jobID, len(unexported))
+	}
+	if hardErr != nil {
+		return errors.Wrapf(hardErr, "Export job %s had errors", exp.jobID)
+	}
+
+	manifestURL := exp.targetURL
+	if exp.collecting() {
+		// data files were moved to this host, the manifest goes with them
+		manifestURL = exp.collectDir
+	}
+	manifestFile, err := exp.manifest.Save(manifestURL, exp.logger)
 	if err != nil {
-		return errors.Wrapf(err, "unable to save results files to >%s<", fileName)
+		return errors.Wrapf(err, "Unable to save manifest")
 	}
-	for _, ff := range finalizedFiles {
-		fmt.Fprintf(fp, "%s\t%s\t%d\t%d\t%s\t%t\n",
-			ff.FileName, ff.KeyRange, ff.ContentSize,
-			ff.RowCount, ff.Checksum, ff.ShellOnly)
+	exp.logger.Info("Manifest saved",
+		zap.String("url", manifestURL),
+		zap.String("file", manifestFile),
+		zap.Int("files", len(exp.manifest.Files)))
+
+	// The manifest now has everything the journal had
+	err = exp.journal.Clear()
+	if err != nil {
+		exp.logger.Warn("Unable to clear job journal", zap.String("job-id", exp.jobID), zap.Error(err))
 	}
-	fp.Close()
 	return nil
 }
 
-func (exp *ExporterClient) ScheduleFetch(exportPlan map[string]exportGroup) (err error) {
+// estimateSize gives exp.progress the expected number of bytes, for the
+// ETA, from the estimates made while planning. Without them progress is
+// judged by the number of ranges done.
+func (exp *ExporterClient) estimateSize(exportPlan map[string]exportGroup) {
+	if exp.sizes == nil {
+		return
+	}
+	var total int64
+	for _, eg := range exportPlan {
+		total += eg.bytes
+	}
+	exp.progress.Estimate(0, total)
+}
 
+// manifestDirectories lists dirs by path. The root directory
+// (empty path) has no prefix of its own and is left out.
+func manifestDirectories(dirs fdbstat.DirListing) (mdirs []manifest.Directory) {
+	for path, dir := range dirs {
+		if path == "" || path == journal.JOURNAL_DIRECTORY || strings.HasPrefix(path, journal.JOURNAL_DIRECTORY+"/") {
+			continue // not exported
+		}
+		mdirs = append(mdirs, manifest.Directory{
+			Path:            path,
+			Prefix:          dir.Prefix,
+			PrefixPrintable: dir.PrefixPrintable,
+		})
+	}
+	sort.Slice(mdirs, func(i, j int) bool {
+		return mdirs[i].Path < mdirs[j].Path
+	})
+	return mdirs
+}
+
+// fetchRound runs one session per host in exportPlan concurrently and
+// returns the ranges that failed, by the host they failed on. `err` is set
+// for errors that did not leave ranges unexported (e.g. --collect failures).
+func (exp *ExporterClient) fetchRound(exportPlan map[string]exportGroup) (failed map[string][]fdb.KeyRange, err error) {
+
+	failed = map[string][]fdb.KeyRange{}
+	sched := exp.newScheduler(exportPlan)
+	exp.hosts = len(exportPlan)
 	var wg sync.WaitGroup
+	var lock sync.Mutex
 	for _, plan := range exportPlan {
+		conn, errConn := exp.connect(plan.host)
+		if errConn != nil {
+			// Its ranges stay queued, other replicas can take them
+			exp.logger.Error("Unable to connect", zap.String("host", plan.host), zap.Error(errConn))
+			continue
+		}
+		plan.conn = conn
 		wg.Add(1)
 		go func(plan exportGroup, wg *sync.WaitGroup) {
 			defer wg.Done()
-			err = exp.ScheduleFetchByNode(plan, exp.dryRun)
-			if err != nil {
-				exp.logger.Error("Error from worker thread", zap.Error(err))
+			failedRanges, errWorker := exp.ScheduleFetchByNode(plan, sched, exp.dryRun)
+			if errWorker != nil {
+				exp.logger.Error("Error from worker thread",
+					zap.String("host", plan.host),
+					zap.Int("failed-ranges", len(failedRanges)),
+					zap.Error(errWorker))
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			if len(failedRanges) > 0 {
+				failed[plan.host] = append(failed[plan.host], failedRanges...)
+			} else if errWorker != nil {
+				err = errWorker
 			}
 		}(plan, &wg)
 	}
 	wg.Wait()
+
+	// Whatever is still queued had no host (left) to take it
+	for host, kranges := range sched.queues {
+		if len(kranges) > 0 {
+			failed[host] = append(failed[host], kranges...)
+		}
+	}
+	return failed, err
+}
+
+// collecting is true when exported files are brought back to
+// this host via --collect (only possible for file:// targets)
+func (exp *ExporterClient) collecting() bool {
+	return exp.collectDir != "" && // --collect /foo/bar argument exists
+		(!strings.Contains(exp.targetURL, "://") || // and it is a raw-path (not a s3:// type URL)
+			strings.HasPrefix(exp.targetURL, "file://")) // OR it is a file:// URL
+}
+
+// decrypts reads all of an encrypted file, which
+// authenticates every chunk, and discards the content
+func decrypts(fileName string, key *format.Key) error {
+	fp, err := os.Open(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to open %s", fileName)
+	}
+	defer fp.Close()
+	r, err := key.NewReader(fp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
 	return err
 }
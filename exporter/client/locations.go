@@ -18,8 +18,12 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"slices"
+	"sort"
 
+	"github.com/adobe/ferry/fdbstat"
 	"github.com/adobe/ferry/finder"
+	"github.com/adobe/ferry/journal"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
@@ -28,37 +32,28 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
+// AssignSources plans every range onto one of its replicas, so that each
+// host gets about the same number of (estimated) bytes to export. Ranges
+// go biggest first, each to the replica with the fewest bytes so far.
 func (exp *ExporterClient) AssignSources(pmap *finder.PartitionMap) (exportPlan map[string]exportGroup, err error) {
 
 	exportPlan = make(map[string]exportGroup) // initialize return struct
 
-	busy := map[string]int{}
+	busy := map[string]int64{}
+	exp.replicas = map[rangeKey][]string{}
+	exp.sizes = exp.rangeSizes(pmap)
+	pmap = exp.splitLarge(pmap)
 
-	b, err := os.ReadFile(exp.caFile)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to read CA file: >%s<", exp.caFile)
-	}
-	cp := x509.NewCertPool()
-	if !cp.AppendCertsFromPEM(b) {
-		return nil, errors.New("credentials: failed to append certificates")
-	}
-	config := &tls.Config{
-		InsecureSkipVerify: true, // TODO: Fix the TLS issue
-		RootCAs:            cp,
-		ServerName:         "adobe.net",
-	}
-	/*
-		creds, err := credentials.NewClientTLSFromFile(exp.caFile, "adobe.net")
-		if err != nil {
-			exp.logger.Warn("Failed to read TLS credentials", zap.String("ca-file", exp.caFile))
-			return nil, errors.Wrapf(err, "Failed to read TLS credentials from %s", exp.caFile)
-		}
-	*/
+	ranges := append([]finder.RangeLocation(nil), pmap.Ranges...)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return exp.weight(newRangeKey(ranges[i].Krange)) > exp.weight(newRangeKey(ranges[j].Krange))
+	})
 
-	for _, x := range pmap.Ranges {
+	for _, x := range ranges {
 		// find the least busy (alloted) host
+		rk := newRangeKey(x.Krange)
 		least_busy_host := ""
-		current_load := math.MaxInt
+		current_load := int64(math.MaxInt64)
 		all_options_for_range := []string{}
 
 		for _, host := range x.Hosts {
@@ -76,35 +71,258 @@ func (exp *ExporterClient) AssignSources(pmap *finder.PartitionMap) (exportPlan
 		exp.logger.Debug("Range->Host mapping",
 			zap.ByteString("begin", x.Krange.Begin.FDBKey()),
 			zap.ByteString("end", x.Krange.End.FDBKey()),
+			zap.Int64("size", exp.sizes[rk]),
 			zap.String("host", least_busy_host),
-			zap.Int("current_load", current_load),
+			zap.Int64("current_load", current_load),
 			zap.Any("others", all_options_for_range))
 
-		busy[least_busy_host]++
+		busy[least_busy_host] += exp.weight(rk)
+		exp.replicas[rk] = x.Hosts
+		exp.addToPlan(exportPlan, least_busy_host, x.Krange)
+	}
 
-		if _, ok := exportPlan[least_busy_host]; !ok {
-			conn, err := grpc.Dial(fmt.Sprintf("%s:%d", least_busy_host, exp.grpcPort),
-				//grpc.WithTransportCredentials(creds))
-				grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	for k, v := range exportPlan {
+		exp.logger.Debug("EXPORT-PLAN", zap.String("host", k),
+			zap.Int("ranges", len(v.kranges)),
+			zap.Int64("estimated-bytes", v.bytes))
+	}
+	return exportPlan, err
+}
+
+// rangeSizes asks the cluster for the estimated size of every range.
+// nil (planning by range count) if it can't.
+func (exp *ExporterClient) rangeSizes(pmap *finder.PartitionMap) (sizes map[rangeKey]int64) {
 
-			if err != nil {
-				exp.logger.Warn("Failed to dail", zap.String("host", least_busy_host))
-				return nil, errors.Wrapf(err, "Fail to dial: %s", least_busy_host)
+	srvy, err := fdbstat.NewSurveyor(exp.db, fdbstat.Logger(exp.logger))
+	if err != nil {
+		exp.logger.Warn("Unable to estimate range sizes, balancing by range count", zap.Error(err))
+		return nil
+	}
+	sizeByRange, err := srvy.CalculateDBSize(pmap)
+	if err != nil {
+		exp.logger.Warn("Unable to estimate range sizes, balancing by range count", zap.Error(err))
+		return nil
+	}
+	sizes = map[rangeKey]int64{}
+	for _, x := range pmap.Ranges {
+		sizes[newRangeKey(x.Krange)] = sizeByRange[fdbstat.NewHashableKeyRange(x.Krange)].Size
+	}
+	return sizes
+}
+
+// splitLarge cuts ranges estimated over exp.splitBytes into pieces of
+// about that size, so reader threads on several hosts can export them at
+// once (one file each). Pieces are exported from the same replicas and
+// get an equal share of the range's estimated size.
+func (exp *ExporterClient) splitLarge(pmap *finder.PartitionMap) *finder.PartitionMap {
+
+	if exp.splitBytes <= 0 || exp.sizes == nil {
+		return pmap
+	}
+	split := &finder.PartitionMap{}
+	for _, x := range pmap.Ranges {
+		rk := newRangeKey(x.Krange)
+		size := exp.sizes[rk]
+		if size <= exp.splitBytes {
+			split.Ranges = append(split.Ranges, x)
+			continue
+		}
+		pieces, err := finder.SplitRange(exp.db, x.Krange, exp.splitBytes)
+		if err != nil {
+			exp.logger.Warn("Unable to split range, exporting it whole", zap.Error(err))
+			split.Ranges = append(split.Ranges, x)
+			continue
+		}
+		exp.logger.Info("Splitting range",
+			zap.String("begin", fdb.Printable(x.Krange.Begin.FDBKey())),
+			zap.String("end", fdb.Printable(x.Krange.End.FDBKey())),
+			zap.Int64("estimated-bytes", size),
+			zap.Int("pieces", len(pieces)))
+		delete(exp.sizes, rk)
+		for _, piece := range pieces {
+			exp.sizes[newRangeKey(piece)] = size / int64(len(pieces))
+			split.Ranges = append(split.Ranges, finder.RangeLocation{Krange: piece, Hosts: x.Hosts})
+		}
+	}
+	return split
+}
+
+// weight is what a range adds to a host's load: its estimated size, but
+// at least 1 (every range costs something, and without estimates this
+// is balancing by range count).
+func (exp *ExporterClient) weight(rk rangeKey) int64 {
+	if exp.sizes[rk] < 1 {
+		return 1
+	}
+	return exp.sizes[rk]
+}
+
+// ReassignFailed plans ranges that failed on some host onto another replica
+// of that range. `tried` tracks every host a range was attempted on and is
+// updated here. Ranges with no untried replica or no retries left are returned
+// as `exhausted`.
+func (exp *ExporterClient) ReassignFailed(failed map[string][]fdb.KeyRange, tried map[rangeKey][]string) (exportPlan map[string]exportGroup, exhausted []fdb.KeyRange, err error) {
+
+	exportPlan = make(map[string]exportGroup)
+	busy := map[string]int64{}
+
+	for failedHost, kranges := range failed {
+		for _, krange := range kranges {
+			rk := newRangeKey(krange)
+			tried[rk] = append(tried[rk], failedHost)
+			if len(tried[rk]) > exp.maxRetries {
+				exhausted = append(exhausted, krange)
+				continue
+			}
+
+			least_busy_host := ""
+			current_load := int64(math.MaxInt64)
+			// A TRANSIENT failure (e.g. the cluster was busy) says
+			// nothing against the host, so it stays a candidate.
+			transient := exp.transient.contains(rk)
+			for _, host := range exp.replicas[rk] {
+				if contains(tried[rk], host) && !(transient && host == failedHost) {
+					continue
+				}
+				if current_load > busy[host] {
+					least_busy_host = host
+					current_load = busy[host]
+				}
 			}
-			kranges := []fdb.KeyRange{x.Krange}
-			exportPlan[least_busy_host] = exportGroup{
-				kranges: kranges,
-				conn:    ferry.NewFerryClient(conn),
-				host:    least_busy_host}
-		} else {
-			eg := exportPlan[least_busy_host]
-			eg.kranges = append(eg.kranges, x.Krange)
-			exportPlan[least_busy_host] = eg
+			if least_busy_host == "" {
+				exhausted = append(exhausted, krange)
+				continue
+			}
+			exp.logger.Info("Rescheduling range",
+				zap.String("begin", fdb.Printable(krange.Begin.FDBKey())),
+				zap.String("end", fdb.Printable(krange.End.FDBKey())),
+				zap.String("failed-on", failedHost),
+				zap.String("host", least_busy_host),
+				zap.Int("attempt", len(tried[rk])+1))
+
+			busy[least_busy_host] += exp.weight(rk)
+			exp.addToPlan(exportPlan, least_busy_host, krange)
+		}
+	}
+	return exportPlan, exhausted, nil
+}
+
+func (exp *ExporterClient) addToPlan(exportPlan map[string]exportGroup, host string, krange fdb.KeyRange) {
+
+	eg, ok := exportPlan[host]
+	if !ok {
+		eg = exportGroup{host: host}
+	}
+	eg.kranges = append(eg.kranges, krange)
+	eg.bytes += exp.sizes[newRangeKey(krange)]
+	exportPlan[host] = eg
+}
+
+// connect returns a (lazily connecting) client for host, reusing
+// one created earlier for the same host.
+func (exp *ExporterClient) connect(host string) (conn ferry.FerryClient, err error) {
+
+	if conn, ok := exp.conns[host]; ok {
+		return conn, nil
+	}
+
+	b, err := os.ReadFile(exp.caFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read CA file: >%s<", exp.caFile)
+	}
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM(b) {
+		return nil, errors.New("credentials: failed to append certificates")
+	}
+	config := &tls.Config{
+		InsecureSkipVerify: true, // TODO: Fix the TLS issue
+		RootCAs:            cp,
+		ServerName:         "adobe.net",
+	}
+	/*
+		creds, err := credentials.NewClientTLSFromFile(exp.caFile, "adobe.net")
+		if err != nil {
+			exp.logger.Warn("Failed to read TLS credentials", zap.String("ca-file", exp.caFile))
+			return nil, errors.Wrapf(err, "Failed to read TLS credentials from %s", exp.caFile)
 		}
+	*/
+
+	cc, err := grpc.Dial(fmt.Sprintf("%s:%d", host, exp.grpcPort),
+		//grpc.WithTransportCredentials(creds))
+		grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		exp.logger.Warn("Failed to dail", zap.String("host", host))
+		return nil, errors.Wrapf(err, "Fail to dial: %s", host)
+	}
+	if exp.conns == nil {
+		exp.conns = map[string]ferry.FerryClient{}
 	}
+	exp.conns[host] = ferry.NewFerryClient(cc)
+	return exp.conns[host], nil
+}
 
-	for k, v := range exportPlan {
-		exp.logger.Debug("EXPORT-PLAN", zap.String("host", k), zap.Int("ranges", len(v.kranges)))
+// rangeKey makes an fdb.KeyRange usable as a map key
+type rangeKey struct {
+	begin string
+	end   string
+}
+
+func newRangeKey(krange fdb.KeyRange) rangeKey {
+	return rangeKey{
+		begin: string(krange.Begin.FDBKey()),
+		end:   string(krange.End.FDBKey()),
 	}
-	return exportPlan, err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeCompleted drops the export journal's own keys and, for a resumed
+// job, the ranges an earlier run of it has already exported.
+func (exp *ExporterClient) ExcludeCompleted(pmap *finder.PartitionMap) (remaining *finder.PartitionMap, err error) {
+
+	journalRange, err := journal.KeyRange(exp.db)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to find the export journal")
+	}
+	if len(journalRange.Begin.FDBKey()) > 0 {
+		pmap = pmap.Exclude([]fdb.KeyRange{journalRange})
+	}
+	if !exp.resume {
+		return pmap, nil
+	}
+	info, err := exp.journal.Info()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to resume job %s", exp.jobID)
+	}
+	if info.TargetURL != exp.targetURL || info.ExportFormat != exp.exportFormat ||
+		info.Compression != exp.compression || info.Encrypted != exp.encrypt || info.ReadPercent != exp.readPercent ||
+		info.SampleBy != exp.sampleBy || info.SampleSeed != exp.sampleSeed ||
+		!slices.Equal(info.Redaction, exp.redactionSummary()) {
+		return nil, errors.Errorf("Job %s was started with different options: %+v", exp.jobID, *info)
+	}
+	if !info.Scope.Equal(exp.scope) {
+		return nil, errors.Errorf("Job %s was started with a different scope: %+v", exp.jobID, info.Scope)
+	}
+	exp.resumedFiles, err = exp.journal.Completed()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to resume job %s", exp.jobID)
+	}
+	var done []fdb.KeyRange
+	for _, ff := range exp.resumedFiles {
+		done = append(done, fdb.KeyRange{Begin: fdb.Key(ff.BeginKey), End: fdb.Key(ff.EndKey)})
+	}
+	remaining = pmap.Exclude(done)
+	exp.logger.Info("Resuming job",
+		zap.String("job-id", exp.jobID),
+		zap.Time("started", info.StartTime),
+		zap.Int("ranges-done", len(done)),
+		zap.Int("ranges-left", len(remaining.Ranges)))
+	return remaining, nil
 }
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"go.uber.org/zap"
+)
+
+// kr is a key range, named by its begin and end keys
+func kr(begin, end string) fdb.KeyRange {
+	return fdb.KeyRange{Begin: fdb.Key(begin), End: fdb.Key(end)}
+}
+
+func rangeString(krange fdb.KeyRange) string {
+	return fdb.Printable(krange.Begin.FDBKey()) + "-" + fdb.Printable(krange.End.FDBKey())
+}
+
+// testExporter knows where the ranges a-b .. e-f live: h1 holds them all,
+// h2 holds a-b, b-c and c-d, h3 only d-e. Its hosts are never dialed.
+func testExporter() *ExporterClient {
+	return &ExporterClient{
+		logger: zap.NewNop(),
+		replicas: map[rangeKey][]string{
+			newRangeKey(kr("a", "b")): {"h1", "h2"},
+			newRangeKey(kr("b", "c")): {"h1", "h2"},
+			newRangeKey(kr("c", "d")): {"h1", "h2"},
+			newRangeKey(kr("d", "e")): {"h1", "h3"},
+			newRangeKey(kr("e", "f")): {"h1"},
+		},
+		conns: map[string]ferry.FerryClient{"h1": nil, "h2": nil, "h3": nil},
+	}
+}
+
+func planString(exportPlan map[string]exportGroup) string {
+	var s []string
+	for host, eg := range exportPlan {
+		for _, krange := range eg.kranges {
+			s = append(s, rangeString(krange)+"@"+host)
+		}
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
+
+func TestReassignFailed(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRetries    int
+		failed        map[string][]fdb.KeyRange
+		tried         map[string][]string // by range, as a-b
+		transient     []string
+		wantPlan      string
+		wantExhausted string
+	}{
+		{
+			name:       "to another replica",
+			maxRetries: 2,
+			failed:     map[string][]fdb.KeyRange{"h2": {kr("a", "b")}},
+			wantPlan:   "a-b@h1",
+		},
+		{
+			name:          "no other replica",
+			maxRetries:    2,
+			failed:        map[string][]fdb.KeyRange{"h1": {kr("e", "f")}},
+			wantExhausted: "e-f",
+		},
+		{
+			name:          "every replica tried",
+			maxRetries:    5,
+			failed:        map[string][]fdb.KeyRange{"h2": {kr("c", "d")}},
+			tried:         map[string][]string{"c-d": {"h1"}},
+			wantExhausted: "c-d",
+		},
+		{
+			name:          "no retries",
+			maxRetries:    0,
+			failed:        map[string][]fdb.KeyRange{"h2": {kr("a", "b")}},
+			wantExhausted: "a-b",
+		},
+		{
+			name:          "retries used up",
+			maxRetries:    1,
+			failed:        map[string][]fdb.KeyRange{"h3": {kr("d", "e")}},
+			tried:         map[string][]string{"d-e": {"h2"}},
+			wantExhausted: "d-e",
+		},
+		{
+			name:       "transient failure retried on the same host",
+			maxRetries: 2,
+			failed:     map[string][]fdb.KeyRange{"h1": {kr("e", "f")}},
+			transient:  []string{"e-f"},
+			wantPlan:   "e-f@h1",
+		},
+		{
+			name:          "transient failures count against the budget",
+			maxRetries:    2,
+			failed:        map[string][]fdb.KeyRange{"h1": {kr("e", "f")}},
+			tried:         map[string][]string{"e-f": {"h1", "h1"}},
+			transient:     []string{"e-f"},
+			wantExhausted: "e-f",
+		},
+		{
+			name:       "spread over the replicas",
+			maxRetries: 2,
+			failed: map[string][]fdb.KeyRange{
+				"h3": {kr("a", "b"), kr("b", "c")},
+			},
+			wantPlan: "a-b@h1 b-c@h2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := testExporter()
+			exp.maxRetries = tt.maxRetries
+			exp.replicas[newRangeKey(kr("a", "b"))] = []string{"h1", "h2", "h3"}
+			exp.replicas[newRangeKey(kr("b", "c"))] = []string{"h1", "h2", "h3"}
+			tried := map[rangeKey][]string{}
+			for rk := range exp.replicas {
+				tried[rk] = append(tried[rk], tt.tried[rk.begin+"-"+rk.end]...)
+			}
+			for _, r := range tt.transient {
+				begin, end, _ := strings.Cut(r, "-")
+				exp.transient.mark(kr(begin, end), true)
+			}
+
+			plan, exhausted, err := exp.ReassignFailed(tt.failed, tried)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ex []string
+			for _, krange := range exhausted {
+				ex = append(ex, rangeString(krange))
+			}
+			if got := planString(plan); got != tt.wantPlan {
+				t.Errorf("Planned %q, want %q", got, tt.wantPlan)
+			}
+			if got := strings.Join(ex, " "); got != tt.wantExhausted {
+				t.Errorf("Exhausted %q, want %q", got, tt.wantExhausted)
+			}
+			for host, kranges := range tt.failed {
+				for _, krange := range kranges {
+					if !contains(tried[newRangeKey(krange)], host) {
+						t.Errorf("%s not recorded as tried on %s", rangeString(krange), host)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// DATABASE_LOCKED_KEY is where FDB keeps its lock (as `fdbcli lock` sets it):
+// [ versionstamp 10 bytes ] [ lock UID 16 bytes ]. While it is set, only
+// lock-aware transactions can read or write.
+const DATABASE_LOCKED_KEY = "\xff/dbLocked"
+
+// dbLock is a lock this process holds on the database
+type dbLock struct {
+	db      fdb.Database
+	uid     []byte // 16 bytes, two uint64 LE as FDB's UID
+	version int64  // commit version of the lock, nothing changes after it
+}
+
+// lockDatabase locks db the way the management API does. Fails if
+// someone else holds the lock.
+func lockDatabase(db fdb.Database) (lock *dbLock, err error) {
+
+	lock = &dbLock{db: db, uid: make([]byte, 16)}
+	_, err = rand.Read(lock.uid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to create lock UID")
+	}
+	_, err = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		err := lock.options(tr)
+		if err != nil {
+			return nil, err
+		}
+		held, err := lock.holder(tr)
+		if err != nil || held {
+			return nil, err // ours, from an attempt that did commit
+		}
+		// The versionstamp goes at offset 0 (the trailing uint32 LE)
+		param := append(append(make([]byte, 10), lock.uid...), 0, 0, 0, 0)
+		tr.SetVersionstampedValue(fdb.Key(DATABASE_LOCKED_KEY), param)
+		// Conflict with every write in flight, as the management API does
+		return nil, tr.AddWriteConflictRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("\xff")})
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to lock the database")
+	}
+
+	ret, err := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		err := lock.options(tr)
+		if err != nil {
+			return nil, err
+		}
+		return tr.Get(fdb.Key(DATABASE_LOCKED_KEY)).Get()
+	})
+	value, _ := ret.([]byte)
+	if err == nil && len(value) < 8 {
+		err = errors.New("Lock is gone")
+	}
+	if err != nil {
+		if uerr := lock.unlock(); uerr != nil {
+			return nil, errors.Wrapf(err, "Unable to read the lock version, and %s", uerr)
+		}
+		return nil, errors.Wrapf(err, "Unable to read the lock version")
+	}
+	lock.version = int64(binary.BigEndian.Uint64(value))
+	return lock, nil
+}
+
+// unlock releases the lock, if it is still ours
+func (lock *dbLock) unlock() (err error) {
+	_, err = lock.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		err := lock.options(tr)
+		if err != nil {
+			return nil, err
+		}
+		held, err := lock.holder(tr)
+		if err != nil || !held {
+			return nil, err
+		}
+		tr.Clear(fdb.Key(DATABASE_LOCKED_KEY))
+		return nil, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Unable to unlock the database (lock %s)", lock)
+	}
+	return nil
+}
+
+// holder is true if the lock is ours, false if the database is not
+// locked, and an error if someone else locked it.
+func (lock *dbLock) holder(tr fdb.Transaction) (ours bool, err error) {
+	value, err := tr.Get(fdb.Key(DATABASE_LOCKED_KEY)).Get()
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return false, nil
+	}
+	if len(value) >= 10 && bytes.Equal(value[10:], lock.uid) {
+		return true, nil
+	}
+	return false, errors.Errorf("The database is locked by someone else (%s)", fdb.Printable(value))
+}
+
+func (lock *dbLock) options(tr fdb.Transaction) (err error) {
+	err = tr.Options().SetAccessSystemKeys()
+	if err != nil {
+		return err
+	}
+	return tr.Options().SetLockAware()
+}
+
+// String is the UID as fdbcli shows it, and `fdbcli unlock` takes it
+func (lock *dbLock) String() string {
+	return fmt.Sprintf("%016x%016x",
+		binary.LittleEndian.Uint64(lock.uid[:8]),
+		binary.LittleEndian.Uint64(lock.uid[8:]))
+}
+
+// holdLock makes sure lock is released: by calling `release`, or on
+// SIGINT/SIGTERM, after which the signal is raised again so the process
+// ends as it would have. Only SIGKILL (or a crash) leaves it locked.
+func (exp *ExporterClient) holdLock(lock *dbLock) (release func()) {
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+			err := lock.unlock()
+			if err != nil {
+				exp.logger.Error("Database left locked, unlock it with `fdbcli --exec 'unlock <lock-uid>'`",
+					zap.String("lock-uid", lock.String()),
+					zap.Error(err))
+				return
+			}
+			exp.logger.Info("Database unlocked", zap.String("lock-uid", lock.String()))
+		})
+	}
+	go func() {
+		select {
+		case sig := <-sigs:
+			exp.logger.Warn("Interrupted, unlocking the database", zap.String("signal", sig.String()))
+			release()
+			p, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+	return release
+}
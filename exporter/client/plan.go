@@ -0,0 +1,85 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+)
+
+// Plan is an export plan as printed by `ferry export --plan-only`.
+// Sizes are the cluster's estimates, 0 if it could not give any.
+type Plan struct {
+	EstimatedBytes int64      `json:"estimated_bytes"`
+	Ranges         int        `json:"ranges"`
+	Hosts          []HostPlan `json:"hosts"`
+}
+
+type HostPlan struct {
+	Host           string      `json:"host"`
+	EstimatedBytes int64       `json:"estimated_bytes"`
+	Ranges         []RangePlan `json:"ranges"`
+}
+
+// RangePlan keys are in fdb.Printable form
+type RangePlan struct {
+	Begin          string   `json:"begin"`
+	End            string   `json:"end"`
+	EstimatedBytes int64    `json:"estimated_bytes"`
+	Replicas       []string `json:"replicas"`
+}
+
+// GetPlan describes exportPlan (from AssignSources), hosts sorted by
+// name and each host's ranges by key.
+func (exp *ExporterClient) GetPlan(exportPlan map[string]exportGroup) (plan Plan) {
+
+	plan.Hosts = []HostPlan{}
+	for host, eg := range exportPlan {
+		hp := HostPlan{Host: host, EstimatedBytes: eg.bytes, Ranges: []RangePlan{}}
+		kranges := append([]fdb.KeyRange(nil), eg.kranges...)
+		sort.Slice(kranges, func(i, j int) bool {
+			return string(kranges[i].Begin.FDBKey()) < string(kranges[j].Begin.FDBKey())
+		})
+		for _, krange := range kranges {
+			rk := newRangeKey(krange)
+			hp.Ranges = append(hp.Ranges, RangePlan{
+				Begin:          fdb.Printable(krange.Begin.FDBKey()),
+				End:            fdb.Printable(krange.End.FDBKey()),
+				EstimatedBytes: exp.sizes[rk],
+				Replicas:       exp.replicas[rk],
+			})
+		}
+		plan.EstimatedBytes += eg.bytes
+		plan.Ranges += len(eg.kranges)
+		plan.Hosts = append(plan.Hosts, hp)
+	}
+	sort.Slice(plan.Hosts, func(i, j int) bool {
+		return plan.Hosts[i].Host < plan.Hosts[j].Host
+	})
+	return plan
+}
+
+// WritePlan writes GetPlan(exportPlan) to w as indented JSON
+func (exp *ExporterClient) WritePlan(w io.Writer, exportPlan map[string]exportGroup) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(exp.GetPlan(exportPlan))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write export plan")
+	}
+	return nil
+}
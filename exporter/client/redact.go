@@ -0,0 +1,147 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adobe/ferry/exporter/session"
+	"github.com/adobe/ferry/finder"
+	"github.com/adobe/ferry/manifest"
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/pkg/errors"
+)
+
+// RedactRule is one entry of `redact.rules` in .ferry.yaml. Directory
+// (a/b/c, and its subdirectories) or Prefix (\xNN escapes allowed)
+// picks the keys whose values it masks. Action is one of
+// drop|null|hash|tokenize|replace, Element the 1-based tuple element of
+// the value to mask (0 for the whole value), Value what replace puts in.
+type RedactRule struct {
+	Directory string
+	Prefix    string
+	Action    string
+	Element   int
+	Value     string
+}
+
+func (r RedactRule) String() string {
+	return fmt.Sprintf("directory=%s prefix=%s action=%s element=%d value=%q",
+		r.Directory, r.Prefix, r.Action, r.Element, r.Value)
+}
+
+// Redact has every node mask values matching `rules` before writing
+// them. The rules are recorded in the manifest.
+func Redact(rules []RedactRule) ExporterOption {
+	return func(exp *ExporterClient) {
+		exp.redactRules = rules
+	}
+}
+
+// resolveRedaction turns the configured rules into prefix rules for
+// the nodes, one per directory and subdirectory (the directory layer
+// gives subdirectories unrelated prefixes), and into what the manifest
+// records of them.
+func (exp *ExporterClient) resolveRedaction() (err error) {
+
+	var rules []session.RedactRule
+	for _, rule := range exp.redactRules {
+		var dirs []dirPrefix
+		switch {
+		case rule.Directory != "" && rule.Prefix != "":
+			return errors.Errorf("Redaction rule %s has both a directory and a prefix", rule)
+		case rule.Directory != "":
+			dirs, err = directoryPrefixes(exp.db, strings.Split(strings.Trim(rule.Directory, "/"), "/"))
+			if err != nil {
+				return err
+			}
+		case rule.Prefix != "":
+			prefix, err := finder.ParsePrintable(rule.Prefix)
+			if err != nil {
+				return err
+			}
+			dirs = []dirPrefix{{prefix: prefix}}
+		default:
+			return errors.Errorf("Redaction rule %s needs a directory or a prefix", rule)
+		}
+		for _, d := range dirs {
+			rules = append(rules, session.RedactRule{
+				Prefix:  d.prefix,
+				Action:  rule.Action,
+				Element: rule.Element,
+				Value:   []byte(rule.Value),
+			})
+			exp.redacted = append(exp.redacted, manifest.RedactRule{
+				Directory:       d.path,
+				Prefix:          d.prefix,
+				PrefixPrintable: fdb.Printable(d.prefix),
+				Action:          rule.Action,
+				Element:         rule.Element,
+				Value:           rule.Value,
+			})
+		}
+	}
+
+	err = session.ValidRedactRules(rules)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		exp.redact = append(exp.redact, &ferry.RedactRule{
+			Prefix:  r.Prefix,
+			Action:  r.Action,
+			Element: int32(r.Element),
+			Value:   r.Value,
+		})
+	}
+	return nil
+}
+
+type dirPrefix struct {
+	path   string // a/b/c, "" for prefix rules
+	prefix []byte
+}
+
+// directoryPrefixes of the directory at path and all below it
+func directoryPrefixes(db fdb.Database, path []string) (dirs []dirPrefix, err error) {
+
+	ds, err := directory.Open(db, path, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to open directory %s", strings.Join(path, "/"))
+	}
+	dirs = append(dirs, dirPrefix{path: strings.Join(path, "/"), prefix: ds.Bytes()})
+	children, err := ds.List(db, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to list directory %s", strings.Join(path, "/"))
+	}
+	for _, child := range children {
+		sub, err := directoryPrefixes(db, append(append([]string(nil), path...), child))
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, sub...)
+	}
+	return dirs, nil
+}
+
+// redactionSummary is what the journal keeps of the rules, so a
+// resumed job can be checked to mask the same way
+func (exp *ExporterClient) redactionSummary() (summary []string) {
+	for _, r := range exp.redactRules {
+		summary = append(summary, r.String())
+	}
+	return summary
+}
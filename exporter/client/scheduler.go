@@ -0,0 +1,96 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// scheduler hands out the ranges of one round on demand. Each host starts
+// with the ranges AssignSources planned for it. A host that runs out takes
+// over ranges still queued for another host, provided it has a replica of
+// them - so fast hosts end up doing more and slow ones don't hold the
+// job back.
+type scheduler struct {
+	sync.Mutex
+	queues   map[string][]fdb.KeyRange // not handed out yet, biggest first
+	replicas map[rangeKey][]string
+	weight   func(rk rangeKey) int64
+}
+
+func (exp *ExporterClient) newScheduler(exportPlan map[string]exportGroup) *scheduler {
+	s := &scheduler{
+		queues:   map[string][]fdb.KeyRange{},
+		replicas: exp.replicas,
+		weight:   exp.weight,
+	}
+	for host, eg := range exportPlan {
+		s.queues[host] = append([]fdb.KeyRange(nil), eg.kranges...)
+	}
+	return s
+}
+
+// next returns the next range for host to export. ok is false when there
+// is nothing left that host can export.
+func (s *scheduler) next(host string) (krange fdb.KeyRange, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if q := s.queues[host]; len(q) > 0 {
+		s.queues[host] = q[1:]
+		return q[0], true
+	}
+
+	// Steal from whoever has the most (estimated bytes) left. Take from
+	// the back of their queue, the end they would get to last.
+	victim, victimLoad, at := "", int64(0), -1
+	for other, q := range s.queues {
+		if other == host {
+			continue
+		}
+		load := int64(0)
+		candidate := -1
+		for i, kr := range q {
+			rk := newRangeKey(kr)
+			load += s.weight(rk)
+			if contains(s.replicas[rk], host) {
+				candidate = i
+			}
+		}
+		if candidate >= 0 && load > victimLoad {
+			victim, victimLoad, at = other, load, candidate
+		}
+	}
+	if victim == "" {
+		return krange, false
+	}
+	q := s.queues[victim]
+	krange = q[at]
+	s.queues[victim] = append(q[:at:at], q[at+1:]...)
+	return krange, true
+}
+
+// nextOwn is next, without stealing
+func (s *scheduler) nextOwn(host string) (krange fdb.KeyRange, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	q := s.queues[host]
+	if len(q) == 0 {
+		return krange, false
+	}
+	s.queues[host] = q[1:]
+	return q[0], true
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+func TestSchedulerSteal(t *testing.T) {
+	tests := []struct {
+		name  string
+		plan  map[string][]fdb.KeyRange
+		sizes map[string]int64 // by range, as a-b
+		calls []string         // host:expected range, "" for none left
+	}{
+		{
+			name:  "own ranges first, in order",
+			plan:  map[string][]fdb.KeyRange{"h1": {kr("a", "b"), kr("b", "c")}},
+			calls: []string{"h1:a-b", "h1:b-c", "h1:"},
+		},
+		{
+			name: "steals from the back of a queue",
+			plan: map[string][]fdb.KeyRange{
+				"h1": {kr("a", "b"), kr("b", "c"), kr("c", "d")},
+				"h2": {},
+			},
+			calls: []string{"h2:c-d", "h1:a-b", "h2:b-c", "h2:", "h1:"},
+		},
+		{
+			name: "only ranges it has a replica of",
+			plan: map[string][]fdb.KeyRange{
+				"h1": {kr("a", "b"), kr("d", "e"), kr("e", "f")},
+				"h3": {},
+			},
+			calls: []string{"h3:d-e", "h3:", "h1:a-b", "h1:e-f", "h1:"},
+		},
+		{
+			name: "from the host with the most bytes left",
+			plan: map[string][]fdb.KeyRange{
+				"h1": {},
+				"h2": {kr("a", "b"), kr("b", "c")},
+				"h3": {kr("d", "e")},
+			},
+			sizes: map[string]int64{"d-e": 100, "a-b": 10, "b-c": 10},
+			calls: []string{"h1:d-e", "h1:b-c", "h1:a-b", "h1:", "h2:"},
+		},
+		{
+			name: "by range count without estimates",
+			plan: map[string][]fdb.KeyRange{
+				"h1": {},
+				"h2": {kr("e", "f")},
+				"h4": {kr("a", "b"), kr("b", "c"), kr("c", "d")},
+			},
+			calls: []string{"h1:c-d", "h1:b-c", "h4:a-b", "h1:e-f", "h1:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := testExporter()
+			exp.sizes = map[rangeKey]int64{}
+			for rk := range exp.replicas {
+				exp.sizes[rk] = tt.sizes[rk.begin+"-"+rk.end]
+			}
+			plan := map[string]exportGroup{}
+			for host, kranges := range tt.plan {
+				plan[host] = exportGroup{host: host, kranges: kranges}
+			}
+			s := exp.newScheduler(plan)
+			for i, call := range tt.calls {
+				host, want, _ := strings.Cut(call, ":")
+				krange, ok := s.next(host)
+				got := ""
+				if ok {
+					got = rangeString(krange)
+				}
+				if got != want {
+					t.Fatalf("Call %d: next(%s) = %q, want %q", i, host, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerNextOwn(t *testing.T) {
+	exp := testExporter()
+	s := exp.newScheduler(map[string]exportGroup{
+		"h1": {host: "h1", kranges: []fdb.KeyRange{kr("a", "b"), kr("b", "c")}},
+		"h2": {host: "h2"},
+	})
+	if _, ok := s.nextOwn("h2"); ok {
+		t.Error("nextOwn took a range of another host")
+	}
+	for _, want := range []string{"a-b", "b-c"} {
+		krange, ok := s.nextOwn("h1")
+		if !ok || rangeString(krange) != want {
+			t.Errorf("nextOwn(h1) = %s, %v, want %s", rangeString(krange), ok, want)
+		}
+	}
+}
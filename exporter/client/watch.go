@@ -0,0 +1,90 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"go.uber.org/zap"
+)
+
+// transientRanges are the ranges whose last failure was TRANSIENT.
+// Those can be retried on the host they failed on.
+type transientRanges struct {
+	sync.Mutex
+	ranges map[rangeKey]bool
+}
+
+func (t *transientRanges) mark(krange fdb.KeyRange, transient bool) {
+	t.Lock()
+	defer t.Unlock()
+	if t.ranges == nil {
+		t.ranges = map[rangeKey]bool{}
+	}
+	t.ranges[newRangeKey(krange)] = transient
+}
+
+func (t *transientRanges) contains(rk rangeKey) bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.ranges[rk]
+}
+
+// watchSession follows the ranges of sessionID on eg.host as they finish,
+// passing each on to `finished`. The channel is closed once the server ends
+// the stream, which it does when the session is stopped - or right away if
+// the server can't be watched (it does not have WatchExportSession).
+func (exp *ExporterClient) watchSession(ctx context.Context, eg exportGroup, sessionID string, buffer int) (finished chan *ferry.KeyRangeResponse) {
+
+	finished = make(chan *ferry.KeyRangeResponse, buffer)
+	watch, err := eg.conn.WatchExportSession(ctx, &ferry.Session{SessionId: sessionID})
+	if err != nil {
+		exp.logger.Warn("Unable to watch export session, no progress from this host",
+			zap.String("host", eg.host),
+			zap.Error(err))
+		close(finished)
+		return finished
+	}
+	go func() {
+		defer close(finished)
+		for {
+			r, err := watch.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				exp.logger.Warn("Watch of export session ended",
+					zap.String("host", eg.host),
+					zap.Error(err))
+				return
+			}
+			exp.progress.Add(r)
+			exp.logger.Debug("Range finished",
+				zap.String("host", eg.host),
+				zap.String("begin", fdb.Printable(r.BeginKey)),
+				zap.String("end", fdb.Printable(r.EndKey)),
+				zap.String("status", r.Status.String()),
+				zap.Int64("keys", r.RowCount),
+				zap.Int64("bytes", r.Bytes),
+				zap.Int32("retries", r.Retries),
+				zap.Duration("duration", time.Duration(r.EndTime-r.StartTime)))
+			finished <- r
+		}
+	}()
+	return finished
+}
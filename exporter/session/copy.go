@@ -0,0 +1,138 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"fmt"
+	"time"
+
+	importer "github.com/adobe/ferry/importer/session"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const EXPORT_FORMAT_COPY = "copy" // no files, records go straight to another cluster
+
+// NewCopySession returns a session that writes every range sent to it
+// into `dest` (a session on the destination cluster) instead of files.
+func NewCopySession(db fdb.Database, dest *importer.ImporterSession, readerThreads int, logger *zap.Logger) (es *ExporterSession, err error) {
+	return newSession(db, "", logger, Options{
+		ReaderThreads: readerThreads,
+		ReadPercent:   100,
+		ExportFormat:  EXPORT_FORMAT_COPY,
+	}, dest)
+}
+
+// copyRange reads keyRange and streams it into es.dest. Reading and
+// writing run concurrently, connected by a channel of records.
+func (es *ExporterSession) copyRange(thread int, keyRange fdb.KeyRange) (stat readerStat, err error) {
+
+	startTime := time.Now()
+	rangeIdentifier := fmt.Sprintf("%s-%s",
+		fdb.Printable(keyRange.Begin.FDBKey()),
+		fdb.Printable(keyRange.End.FDBKey()))
+
+	txn, err := es.newTransaction()
+	if err != nil {
+		return stat, err
+	}
+
+	pipe := newRecordPipe()
+	written := make(chan error, 1)
+	var keysWritten int64
+	go func() {
+		var err error
+		keysWritten, _, err = es.dest.ImportRecords(thread, rangeIdentifier, pipe)
+		pipe.stop() // unblocks the reader if writing failed
+		written <- err
+	}()
+
+	stat, err = es.readRange(thread, txn, keyRange, pipe.send)
+	pipe.close()
+	errWrite := <-written
+	if errWrite != nil {
+		return stat, errors.Wrapf(errWrite, "Unable to write to destination cluster")
+	}
+	if err != nil {
+		return stat, err
+	}
+
+	es.addRangeResult(RangeResult{
+		KeyRange:  keyRange,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Rows:      stat.keysRead,
+		Bytes:     stat.bytesSaved,
+		Retries:   stat.retries,
+	})
+	es.logger.Info("Range copied",
+		zap.Int("thread", thread),
+		zap.String("range", rangeIdentifier),
+		zap.Int64("keys-read", stat.keysRead),
+		zap.Int64("keys-written", keysWritten),
+		zap.Int64("bytes", stat.bytesSaved),
+		zap.Duration("duration", time.Since(startTime)))
+	return stat, nil
+}
+
+// recordPipe hands records from readRange (send) to ImportRecords
+// (Next/Key/Value/Err) running in another goroutine.
+type recordPipe struct {
+	records chan fdb.KeyValue
+	done    chan struct{} // closed by the writer if it gives up early
+	current fdb.KeyValue
+}
+
+func newRecordPipe() *recordPipe {
+	return &recordPipe{
+		records: make(chan fdb.KeyValue, 1000),
+		done:    make(chan struct{}),
+	}
+}
+
+func (p *recordPipe) send(kv fdb.KeyValue) (n int, err error) {
+	select {
+	case p.records <- kv:
+		return len(kv.Key) + len(kv.Value), nil
+	case <-p.done:
+		return 0, errors.New("Destination stopped accepting records")
+	}
+}
+
+func (p *recordPipe) close() {
+	close(p.records)
+}
+
+func (p *recordPipe) stop() {
+	close(p.done)
+}
+
+func (p *recordPipe) Next() bool {
+	kv, ok := <-p.records
+	p.current = kv
+	return ok
+}
+
+func (p *recordPipe) Key() []byte {
+	return p.current.Key
+}
+
+func (p *recordPipe) Value() []byte {
+	return p.current.Value
+}
+
+// Err is always nil - read errors are reported by readRange
+func (p *recordPipe) Err() error {
+	return nil
+}
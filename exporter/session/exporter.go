@@ -13,9 +13,15 @@ governing permissions and limitations under the License.
 package session
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/adobe/blackhole/lib/archive/common"
+	"github.com/adobe/ferry/format"
+	importer "github.com/adobe/ferry/importer/session"
+	"github.com/adobe/ferry/journal"
+	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -25,7 +31,8 @@ import (
 type ExporterSession struct {
 	db             fdb.Database
 	readerThreads  int
-	compress       bool
+	codec          format.Codec
+	key            *format.Key // encrypts files, nil if not encrypting
 	targetURL      string
 	sessionID      string
 	readerKeysChan chan fdb.KeyRange
@@ -33,39 +40,188 @@ type ExporterSession struct {
 	wgReaders      *sync.WaitGroup
 	wgStaters      *sync.WaitGroup
 	logger         *zap.Logger
-	readPercent    int
+	sampler        *sampler
 	exportFormat   string
+	maxFileSize    int64                     // roll over to a new file past this many bytes, 0 for no limit
+	redactor       *redactor                 // nil if nothing is redacted
+	lockAware      bool                      // read while the database is locked (export --lock)
+	journal        *journal.Journal          // nil unless part of an export job
+	host           string                    // journaled with each file, so a resume knows where it is
+	dest           *importer.ImporterSession // set for copy sessions only
+	throttle       Throttle
+	bytesLimit     *limiter // nil if unlimited
+	keysLimit      *limiter
+	backoff        backoff
+	directories    directories // for EXPORT_FORMAT_KEYS_TUPLE
 	results        Results
 	// state          SessionState
 }
 
 type Results struct {
 	finalizedFiles   map[string]bool
-	finalizedDetails map[string]common.ArchiveFileDetails
+	finalizedDetails map[string]FinalizedDetails
+	ranges           []RangeResult
+	finalized        bool       // no more ranges will be added
+	changed          *sync.Cond // signalled when either of the above changes
 	sync.Mutex
 	// To facilitate concurrent access to slice above
 	// since slice is updated at end-of-run only, the
 	// performance penalty is OK.
 }
 
+// add records r. Caller must hold the lock.
+func (res *Results) add(r RangeResult) {
+	res.ranges = append(res.ranges, r)
+	res.changed.Broadcast()
+}
+
+// FinalizedDetails extends the archive's own bookkeeping with what
+// we know about the range that went into the file.
+type FinalizedDetails struct {
+	common.ArchiveFileDetails
+	KeyRange      fdb.KeyRange
+	StartTime     time.Time
+	EndTime       time.Time
+	Compression   string
+	FormatVersion int
+	ExportFormat  string
+	KeyID         string // encryption key, "" if not encrypted
+}
+
+// RangeResult is the outcome of a single range sent to the session.
+type RangeResult struct {
+	KeyRange  fdb.KeyRange
+	FileName  string // empty if the range had no data, the last one if it took several
+	Files     []*ferry.FinalizedFile
+	StartTime time.Time
+	EndTime   time.Time
+	Rows      int64 // keys read
+	Bytes     int64
+	Retries   int   // transactions restarted while reading
+	Err       error // nil on success
+}
+
+func (r RangeResult) Proto() *ferry.KeyRangeResponse {
+	x := &ferry.KeyRangeResponse{
+		BeginKey:  r.KeyRange.Begin.FDBKey(),
+		EndKey:    r.KeyRange.End.FDBKey(),
+		StartTime: r.StartTime.UnixNano(),
+		EndTime:   r.EndTime.UnixNano(),
+		Status:    ferry.KeyRangeResponse_SUCCESS,
+		FileUrl:   r.FileName,
+		RowCount:  r.Rows,
+		Bytes:     r.Bytes,
+		Retries:   int32(r.Retries),
+		Files:     r.Files,
+	}
+	if r.Err != nil {
+		x.Status = ferry.KeyRangeResponse_FAILURE
+		if isTransient(r.Err) {
+			x.Status = ferry.KeyRangeResponse_TRANSIENT
+		}
+		x.ErrorDetails = r.Err.Error()
+	}
+	return x
+}
+
+// isTransient is true for FDB errors that say "try again (later)", as
+// opposed to something being wrong with the range or where it is written.
+func isTransient(err error) bool {
+	errFDB, ok := errors.Cause(err).(fdb.Error)
+	if !ok {
+		return false
+	}
+	switch errFDB.Code {
+	case 1004, // timed_out
+		1007, // transaction_too_old
+		1009, // future_version
+		1020, // not_committed
+		1031, // transaction_timed_out
+		1037, // process_behind
+		1213: // tag_throttled
+		return true
+	}
+	return false
+}
+
+func (v FinalizedDetails) Proto(keyRange string) *ferry.FinalizedFile {
+	return &ferry.FinalizedFile{
+		FileName:      v.FileName,
+		KeyRange:      keyRange,
+		Checksum:      v.Checksum,
+		ContentSize:   v.BytesWritten,
+		RowCount:      v.RowsWritten,
+		BeginKey:      v.KeyRange.Begin.FDBKey(),
+		EndKey:        v.KeyRange.End.FDBKey(),
+		StartTime:     v.StartTime.UnixNano(),
+		EndTime:       v.EndTime.UnixNano(),
+		Compression:   v.Compression,
+		FormatVersion: int32(v.FormatVersion),
+		ExportFormat:  v.ExportFormat,
+		KeyId:         v.KeyID,
+	}
+}
+
 type readerStat struct {
 	keysRead   int64
 	bytesSaved int64
+	retries    int
 	//fileName   string
 }
 
-func NewSession(db fdb.Database, targetURL string, readerThreads int, compress bool, logger *zap.Logger, readPercent int, exportFormat string) (es *ExporterSession, err error) {
+// Options of an export session
+type Options struct {
+	ReaderThreads int    // at least 1
+	Compression   string // as understood by format.ParseCodec
+	ReadPercent   int    // of the keys exported, picked as set by Sampling
+	Sampling      Sampling
+	ExportFormat  string
+	JobID         string // journal finished ranges under this job, "" for none
+	Throttle      Throttle
+	Key           *format.Key // encrypts files, if set
+	// A range is split over several files if it exceeds MaxFileSize
+	// (bytes as stored), 0 for one file per range
+	MaxFileSize int64
+	Redaction   Redaction // masks values before they are written
+	// With LockAware, every read (and the journal) goes through while
+	// the client holds the database lock
+	LockAware bool
+	Host      string // this node as the client knows it, journaled with each file
+}
+
+// NewSession starts opts.ReaderThreads readers, writing files to targetURL
+func NewSession(db fdb.Database, targetURL string, logger *zap.Logger, opts Options) (es *ExporterSession, err error) {
+	return newSession(db, targetURL, logger, opts, nil)
+}
 
+func newSession(db fdb.Database, targetURL string, logger *zap.Logger, opts Options, dest *importer.ImporterSession) (es *ExporterSession, err error) {
+
+	if dest == nil && !validExportFormat(opts.ExportFormat) {
+		return nil, errors.Errorf("Unknown export format %s", opts.ExportFormat)
+	}
+	codec, err := format.ParseCodec(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	sampler, err := newSampler(opts.ReadPercent, opts.Sampling)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := newRedactor(opts.Redaction)
+	if err != nil {
+		return nil, err
+	}
 	sessionID, err := uuid.NewRandom()
 	if err != nil {
-		es.logger.Warn("Failed to create a session ID", zap.Error(err))
+		logger.Warn("Failed to create a session ID", zap.Error(err))
 		return nil, errors.Wrap(err, "Failed to create a session ID")
 	}
 	sessionIDstr := sessionID.String()
 	es = &ExporterSession{
 		db:             db,
-		readerThreads:  readerThreads,
-		compress:       compress,
+		readerThreads:  opts.ReaderThreads,
+		codec:          codec,
+		key:            opts.Key,
 		logger:         logger,
 		targetURL:      targetURL,
 		sessionID:      sessionIDstr,
@@ -73,17 +229,32 @@ func NewSession(db fdb.Database, targetURL string, readerThreads int, compress b
 		readerStatChan: make(chan readerStat),
 		wgReaders:      &sync.WaitGroup{},
 		wgStaters:      &sync.WaitGroup{},
-		readPercent:    readPercent,
-		exportFormat:   exportFormat,
+		sampler:        sampler,
+		exportFormat:   opts.ExportFormat,
+		maxFileSize:    opts.MaxFileSize,
+		redactor:       redactor,
+		lockAware:      opts.LockAware,
+		host:           opts.Host,
+		dest:           dest,
+		throttle:       opts.Throttle,
+		bytesLimit:     newLimiter(opts.Throttle.BytesPerSecond),
+		keysLimit:      newLimiter(opts.Throttle.KeysPerSecond),
 	}
 
-	es.results.finalizedDetails = make(map[string]common.ArchiveFileDetails)
+	if opts.JobID != "" {
+		es.journal = journal.New(db, opts.JobID)
+		if opts.LockAware {
+			es.journal.SetLockAware()
+		}
+	}
+	es.results.finalizedDetails = make(map[string]FinalizedDetails)
 	es.results.finalizedFiles = make(map[string]bool)
+	es.results.changed = sync.NewCond(&es.results.Mutex)
 	if es.readerThreads <= 0 {
 		es.readerThreads = 1
 	}
 
-	es.logger.Info("Starting", zap.Int("reader threads", es.readerThreads))
+	es.logger.Info("Starting", zap.Int("reader threads", es.readerThreads), zap.Any("throttle", es.throttle))
 	for i := 0; i < es.readerThreads; i++ {
 		es.wgReaders.Add(1)
 		go func(threadNum int, wg *sync.WaitGroup) {
@@ -99,6 +270,10 @@ func NewSession(db fdb.Database, targetURL string, readerThreads int, compress b
 
 	es.wgStaters.Add(1)
 	go es.printStats(es.wgStaters)
+	if es.throttle.Backoff {
+		es.backoff.stop = make(chan struct{})
+		go es.watchCluster(es.backoff.stop)
+	}
 	return es, nil
 }
 
@@ -115,7 +290,7 @@ func (es *ExporterSession) Send(krange fdb.KeyRange) {
 	es.readerKeysChan <- krange
 }
 
-func (es *ExporterSession) Finalize() (finalizedDetails map[string]common.ArchiveFileDetails) {
+func (es *ExporterSession) Finalize() (finalizedDetails map[string]FinalizedDetails) {
 
 	// ---------------------------------------------------
 	// WARNING: Order of channel close and .Wait()s are
@@ -127,6 +302,11 @@ func (es *ExporterSession) Finalize() (finalizedDetails map[string]common.Archiv
 		close(es.readerKeysChan)
 		es.wgReaders.Wait()
 		es.readerKeysChan = nil
+
+		es.results.Lock()
+		es.results.finalized = true
+		es.results.changed.Broadcast()
+		es.results.Unlock()
 	}
 
 	// ---------------------------------------------------
@@ -138,7 +318,62 @@ func (es *ExporterSession) Finalize() (finalizedDetails map[string]common.Archiv
 		es.wgStaters.Wait()
 		es.readerStatChan = nil
 	}
+	if es.backoff.stop != nil {
+		close(es.backoff.stop)
+		es.backoff.stop = nil
+	}
 
 	es.logger.Warn("Finalize()", zap.Any("files", es.results.finalizedDetails))
 	return es.results.finalizedDetails
 }
+
+// RangeResults lists the outcome of every range, once Finalize()-ed
+func (es *ExporterSession) RangeResults() []RangeResult {
+	es.results.Lock()
+	defer es.results.Unlock()
+	return append([]RangeResult(nil), es.results.ranges...)
+}
+
+func (es *ExporterSession) addRangeResult(r RangeResult) {
+	es.results.Lock()
+	defer es.results.Unlock()
+	es.results.add(r)
+}
+
+// Watch calls fn with the result of every range, in the order they finish,
+// starting from the first one. It returns once the session is finalized
+// and fn has seen all results, or early if fn fails or ctx is done.
+func (es *ExporterSession) Watch(ctx context.Context, fn func(r RangeResult) error) (err error) {
+
+	stop := context.AfterFunc(ctx, func() {
+		es.results.Lock()
+		defer es.results.Unlock()
+		es.results.changed.Broadcast()
+	})
+	defer stop()
+
+	next := 0
+	for {
+		es.results.Lock()
+		for next == len(es.results.ranges) && !es.results.finalized && ctx.Err() == nil {
+			es.results.changed.Wait()
+		}
+		pending := es.results.ranges[next:len(es.results.ranges):len(es.results.ranges)]
+		finalized := es.results.finalized
+		es.results.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, r := range pending {
+			err = fn(r)
+			if err != nil {
+				return err
+			}
+		}
+		next += len(pending)
+		if finalized && len(pending) == 0 {
+			return nil
+		}
+	}
+}
@@ -14,67 +14,30 @@ package session
 
 import (
 	"bytes"
-	"encoding/binary"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"hash"
+	"io"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/adobe/blackhole/lib/archive"
 	"github.com/adobe/blackhole/lib/archive/common"
+	"github.com/adobe/ferry/format"
+	"github.com/adobe/ferry/manifest"
+	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
-const MAX_KEY_LEN = (1 << 14) - 1   // Max 14 bits for its length. We only need 10k. Buffer till 16k
-const MAX_VALUE_LEN = (1 << 18) - 1 // Max 18 bits for its length. We only need 100k. Buffer till 260k
+// Retrying reads that failed with a retryable FDB error
+const READ_RETRY_LIMIT = 10 // in a row, without progress
 
-func (es *ExporterSession) saveRecord(ar archive.Archive, key, value []byte) (bytesTotal int, err error) {
-	const UINT32LEN = 4
-	var lbuf = make([]byte, UINT32LEN)
-	var n int
-
-	keyLen := len(key)
-	valueLen := len(value)
-	if keyLen > MAX_KEY_LEN {
-		return 0, errors.Errorf("Sorry we only support key length up to %d bytes", MAX_KEY_LEN)
-	}
-	if valueLen > MAX_VALUE_LEN {
-		return 0, errors.Errorf("Sorry we only support value length up to %d bytes", MAX_VALUE_LEN)
-	}
-	recordLen := uint32(keyLen<<18 | valueLen)
-
-	binary.LittleEndian.PutUint32(lbuf, recordLen)
-
-	n, err = ar.Write(lbuf)
-	if err != nil {
-		msg := fmt.Sprintf("FATAL: Wrote only %d bytes, %d expected.", n, UINT32LEN)
-		es.logger.Error(msg, zap.Int("wrote", n), zap.Int("expected", UINT32LEN))
-		return 0, errors.Wrap(err, msg)
-	}
-	bytesTotal += n
-
-	n, err = ar.Write(key)
-	if err != nil {
-		msg := fmt.Sprintf("FATAL: Wrote only %d bytes, %d expected.", n, keyLen)
-		es.logger.Error(msg, zap.Int("wrote", n), zap.Int("expected", keyLen))
-		return 0, errors.Wrap(err, msg)
-	}
-	bytesTotal += n
-
-	n, err = ar.Write(value)
-	if err != nil {
-		msg := fmt.Sprintf("FATAL: Wrote only %d bytes, %d expected.", n, valueLen)
-		es.logger.Error(msg, zap.Int("wrote", n), zap.Int("expected", valueLen))
-		return 0, errors.Wrap(err, msg)
-	}
-	bytesTotal += n
-
-	return bytesTotal, err
-}
-
-func (es *ExporterSession) saveKeysPlainText(ar archive.Archive, key []byte) (bytesTotal int, err error) {
+func (es *ExporterSession) saveKeysPlainText(ar io.Writer, key []byte) (bytesTotal int, err error) {
 	var n int
 
 	keyLen := len(key)
@@ -101,11 +64,13 @@ func (es *ExporterSession) printStats(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	var totalKeysRead, totalBytesRead int64
+	var totalRetries int
 	var totalKeysLastPrinted int64
 	var startTime = time.Now()
 	for stat := range es.readerStatChan {
 		totalBytesRead += stat.bytesSaved
 		totalKeysRead += stat.keysRead
+		totalRetries += stat.retries
 		if totalKeysRead-totalKeysLastPrinted > 1_000_000 {
 			seconds := time.Since(startTime).Seconds()
 			es.logger.Info("Progress",
@@ -120,7 +85,10 @@ func (es *ExporterSession) printStats(wg *sync.WaitGroup) {
 	}
 
 	es.results.Lock()
-	es.logger.Info("Session total", zap.Int64("keys", totalKeysRead), zap.Int64("bytes", totalBytesRead))
+	es.logger.Info("Session total",
+		zap.Int64("keys", totalKeysRead),
+		zap.Int64("bytes", totalBytesRead),
+		zap.Int("retries", totalRetries))
 	for k, v := range es.results.finalizedDetails {
 		es.logger.Info("SUMMARY",
 			zap.Any("range", k),
@@ -132,49 +100,388 @@ func (es *ExporterSession) printStats(wg *sync.WaitGroup) {
 
 func (es *ExporterSession) dbReader(thread int) (err error) {
 
-	es.logger.Info("Exporting to", zap.String("targetURL", es.targetURL))
+	if es.dest == nil {
+		es.logger.Info("Exporting to", zap.String("targetURL", es.targetURL))
+	}
 
 	for keyRange := range es.readerKeysChan {
-		err := es.rangeReader(thread, keyRange)
+		es.pause() // before the range's first transaction, see readRange
+		startTime := time.Now()
+		var stat readerStat
+		var err error
+		if es.dest != nil {
+			stat, err = es.copyRange(thread, keyRange)
+		} else {
+			stat, err = es.rangeReader(thread, keyRange)
+		}
 		if err != nil {
-			return errors.Wrap(err, "error from rangeReader")
+			// Report the range as failed and move on. The client
+			// decides whether to retry it, possibly on another host.
+			es.logger.Error("error from rangeReader",
+				zap.Int("thread", thread),
+				zap.String("begin", fdb.Printable(keyRange.Begin.FDBKey())),
+				zap.String("end", fdb.Printable(keyRange.End.FDBKey())),
+				zap.Error(err))
+			es.addRangeResult(RangeResult{
+				KeyRange:  keyRange,
+				StartTime: startTime,
+				EndTime:   time.Now(),
+				Rows:      stat.keysRead,
+				Bytes:     stat.bytesSaved,
+				Retries:   stat.retries,
+				Err:       err,
+			})
 		}
 	}
-	return err
+	return nil
 }
 
-func (es *ExporterSession) rangeReader(thread int, keyRange fdb.KeyRange) (err error) {
+// rangeReader exports keyRange to a file of its own, or to several if
+// it outgrows es.maxFileSize. `stat` is filled in as far as reading
+// got, also when it fails.
+func (es *ExporterSession) rangeReader(thread int, keyRange fdb.KeyRange) (stat readerStat, err error) {
+
+	startTime := time.Now()
+	requestedRange := keyRange
+
+	txn, err := es.newTransaction()
+	if err != nil {
+		return stat, err
+	}
+	readVersion, err := txn.GetReadVersion().Get()
+	if err != nil {
+		return stat, errors.Wrapf(err, "Unable to get read version")
+	}
+
+	// The first file is created up front, as always. Files after it
+	// only once there is a key to put in them, so the last file of a
+	// range always ends at the end of the range.
+	file, err := es.newExportFile(requestedRange.Begin.FDBKey(), requestedRange.End.FDBKey(), readVersion)
+	if err != nil {
+		return stat, err
+	}
+	// Each file is published (renamed or uploaded) as soon as it is full,
+	// so at most one file per reader is staged locally. A failed range
+	// leaves nothing behind, as it is retried as a whole, possibly on
+	// another host: its published files are removed again. For the same
+	// reason they are only journaled once the range is done.
+	var finished []FinalizedDetails
+	defer func() {
+		if err != nil {
+			es.abortExportFile(file)
+			es.unpublishExportFiles(finished)
+		}
+	}()
+	var rollAt fdb.Key // where the next file begins, once the current one is full
+	save := func(kv fdb.KeyValue) (n int, err error) {
+		kv, keep := es.redactor.apply(kv)
+		if !keep {
+			return 0, nil
+		}
+		if rollAt != nil {
+			err = es.closeExportFile(file, rollAt)
+			if err != nil {
+				return 0, err
+			}
+			var fds []FinalizedDetails
+			fds, err = es.publishExportFile(file)
+			if err != nil {
+				return 0, err
+			}
+			finished = append(finished, fds...)
+			file, err = es.newExportFile(rollAt, requestedRange.End.FDBKey(), readVersion)
+			if err != nil {
+				file = nil
+				return 0, err
+			}
+			rollAt = nil
+		}
+		n, err = es.writeRecord(file, kv)
+		if err != nil {
+			return n, err
+		}
+		file.rows++
+		if es.maxFileSize > 0 && file.stored.n >= es.maxFileSize {
+			rollAt = append(append(fdb.Key(nil), kv.Key...), 0x00)
+		}
+		return n, nil
+	}
+
+	stat, err = es.readRange(thread, txn, keyRange, save)
+	if err != nil {
+		return stat, err
+	}
+	err = es.closeExportFile(file, requestedRange.End.FDBKey())
+	if err != nil {
+		return stat, err
+	}
+	fds, err := es.publishExportFile(file)
+	if err != nil {
+		return stat, err
+	}
+	file = nil
+	finished = append(finished, fds...)
+	if len(finished) > 1 {
+		es.logger.Info("Range rolled over",
+			zap.Int("thread", thread),
+			zap.String("begin", fdb.Printable(requestedRange.Begin.FDBKey())),
+			zap.String("end", fdb.Printable(requestedRange.End.FDBKey())),
+			zap.Int("files", len(finished)))
+	}
+
+	rangeIdentifier := fmt.Sprintf("%s-%s",
+		fdb.Printable(keyRange.Begin.FDBKey()),
+		fdb.Printable(keyRange.End.FDBKey()))
+
+	es.results.Lock()
+	var journaled []*ferry.FinalizedFile
+	result := RangeResult{
+		KeyRange:  requestedRange,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Rows:      stat.keysRead,
+		Bytes:     stat.bytesSaved,
+		Retries:   stat.retries,
+	}
+	for _, fd := range finished {
+		fileRange := fmt.Sprintf("%s-%s",
+			fdb.Printable(fd.KeyRange.Begin.FDBKey()),
+			fdb.Printable(fd.KeyRange.End.FDBKey()))
+		es.results.finalizedDetails[fileRange] = fd
+		es.results.finalizedFiles[fd.FileName] = true
+		journaled = append(journaled, fd.Proto(fileRange))
+		result.FileName = fd.FileName
+	}
+	result.Files = journaled
+	es.results.add(result)
+	// es.logger.Debug("Results so far",
+	//
+	//	zap.Any("results", es.results.finalizedDetails))
+	es.results.Unlock()
 
-	ar, err := archive.NewArchive(es.targetURL, "fdb", ".records",
-		common.Compress(es.compress),
+	if es.journal != nil {
+		// An empty range leaves no file behind, but is still done.
+		if len(journaled) == 0 {
+			journaled = append(journaled, &ferry.FinalizedFile{
+				KeyRange:  rangeIdentifier,
+				BeginKey:  requestedRange.Begin.FDBKey(),
+				EndKey:    requestedRange.End.FDBKey(),
+				ShellOnly: true,
+			})
+		}
+		for _, ff := range journaled {
+			ff = proto.Clone(ff).(*ferry.FinalizedFile) // result.Files is sent as is
+			ff.Host = es.host
+			err = es.journal.Record(ff)
+			if err != nil {
+				// Not fatal - the file is there, a resume would just redo this range
+				es.logger.Warn("Unable to journal range",
+					zap.String("range", ff.KeyRange),
+					zap.Error(err))
+			}
+		}
+	}
+	return stat, nil
+}
+
+// exportFile is one file being written for a range: the archive file,
+// with encryption, compression and the export format layered on top.
+type exportFile struct {
+	ar         archive.Archive
+	stored     *countingWriter // what reaches ar, for es.maxFileSize
+	encrypted  io.WriteCloser  // nil if not encrypting, or no record yet
+	compressed io.WriteCloser  // nil until the first record, as are records
+	digest     hash.Hash
+	records    RecordWriter
+	header     format.Header
+	begin, end fdb.Key // as in the header, end is the end of the range
+	fileEnd    fdb.Key // where the keys in the file end, set by closeExportFile
+	startTime  time.Time
+	endTime    time.Time
+	rows       int64
+}
+
+// newExportFile starts a file for the keys from `begin`. `end` (the end
+// of the range) goes into the header; a file that ends before it says
+// so in its trailer, see finishExportFile.
+func (es *ExporterSession) newExportFile(begin, end fdb.Key, readVersion int64) (ef *exportFile, err error) {
+
+	ef = &exportFile{begin: begin, end: end, startTime: time.Now()}
+
+	// Compression is done here rather than by the archive
+	// library, as it has to happen before encryption.
+	extension := fileExtension(es.exportFormat) + es.codec.Extension()
+	if es.key != nil {
+		extension += format.ENCRYPTED_EXTENSION
+	}
+	ef.ar, err = archive.NewArchive(es.targetURL, "fdb", extension,
 		common.BufferSize(4096),
 		common.Logger(es.logger))
 	if err != nil {
-		return errors.Wrapf(err, "Unable to create archive file")
+		return nil, errors.Wrapf(err, "Unable to create archive file")
 	}
-	defer ar.Close()
 
-	txn, err := es.db.CreateTransaction()
-	if err != nil {
-		return errors.Wrapf(err, "Unable to create fdb transaction")
+	// Checksum is over the uncompressed content, so it stays
+	// comparable no matter how the file was stored.
+	ef.digest = sha256.New()
+	ef.stored = &countingWriter{w: ef.ar}
+	ef.header = format.Header{
+		Begin:       begin,
+		End:         end,
+		ReadVersion: readVersion,
+		CreateTime:  ef.startTime,
+	}
+	return ef, nil
+}
+
+// writeRecord adds kv to ef. Encryption, the compressor and the record
+// writer are only opened for the first record: they write a header or
+// frame bytes even with no input, and a file must stay empty for the
+// archive library to discard it.
+func (es *ExporterSession) writeRecord(ef *exportFile, kv fdb.KeyValue) (n int, err error) {
+	if ef.records == nil {
+		stored := io.Writer(ef.stored)
+		if es.key != nil {
+			ef.encrypted, err = es.key.NewWriter(stored)
+			if err != nil {
+				return 0, err
+			}
+			stored = ef.encrypted
+		}
+		ef.compressed, err = es.codec.NewWriter(stored)
+		if err != nil {
+			return 0, err
+		}
+		ef.records, err = es.newRecordWriter(io.MultiWriter(ef.compressed, ef.digest), ef.header)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return ef.records.Write(kv)
+}
+
+// closeExportFile finishes writing ef, which holds the keys up to `end`.
+// If that is not the end in its header, the file records it in its
+// trailer (archive format only, the others have no key range in them).
+// The file stays staged until publishExportFile.
+func (es *ExporterSession) closeExportFile(ef *exportFile, end fdb.Key) (err error) {
+
+	ef.fileEnd = end
+	ef.endTime = time.Now()
+	if ef.records != nil {
+		if rw, ok := ef.records.(rangeEnder); ok && !bytes.Equal(end, ef.end) {
+			rw.SetEnd(end)
+		}
+		err = ef.records.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to finish %s file", es.exportFormat)
+		}
+		err = ef.compressed.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to finish %s compression", es.codec.Name)
+		}
+	}
+	if ef.encrypted != nil {
+		err = ef.encrypted.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to finish encryption")
+		}
 	}
+	return nil
+}
+
+// publishExportFile closes the archive file of ef, which moves it to
+// its final name (or uploads it). A file no record was written to is
+// discarded by the archive library, leaving no details.
+func (es *ExporterSession) publishExportFile(ef *exportFile) (finished []FinalizedDetails, err error) {
 
-	err = txn.Options().SetReadYourWritesDisable()
+	err = ef.ar.Close()
 	if err != nil {
-		return errors.Wrapf(err, "Unable to set transaction option")
+		return nil, errors.Wrapf(err, "Unable to close archive file")
+	}
+
+	formatVersion := format.CURRENT_VERSION
+	if es.exportFormat != EXPORT_FORMAT_ARCHIVE {
+		formatVersion = 1 // other formats have only ever had one layout
 	}
+	for _, v := range ef.ar.FinalizedFiles() {
+		v.RowsWritten = ef.rows
+		v.Checksum = hex.EncodeToString(ef.digest.Sum(nil))
+		fd := FinalizedDetails{
+			ArchiveFileDetails: v,
+			KeyRange:           fdb.KeyRange{Begin: ef.begin, End: ef.fileEnd},
+			StartTime:          ef.startTime,
+			EndTime:            ef.endTime,
+			Compression:        es.codec.String(),
+			FormatVersion:      formatVersion,
+			ExportFormat:       es.exportFormat,
+		}
+		if es.key != nil {
+			fd.KeyID = es.key.ID
+		}
+		finished = append(finished, fd)
+	}
+	return finished, nil
+}
+
+// abortExportFile drops ef without publishing it. Its staged file is
+// removed first, so closing the archive has nothing to rename or upload.
+func (es *ExporterSession) abortExportFile(ef *exportFile) {
+	if ef == nil {
+		return
+	}
+	staged := ef.ar.Name()
+	err := os.Remove(staged)
+	if err != nil && !os.IsNotExist(err) {
+		es.logger.Warn("Unable to remove partial export file", zap.String("file", staged), zap.Error(err))
+	}
+	ef.ar.Close() // fails, there is no file to finalize any more
+}
+
+// unpublishExportFiles removes published files of a range that failed
+func (es *ExporterSession) unpublishExportFiles(finished []FinalizedDetails) {
+	for _, fd := range finished {
+		err := manifest.RemoveFile(es.targetURL, fd.FileName)
+		if err != nil {
+			es.logger.Warn("Unable to remove export file of a failed range",
+				zap.String("file", fd.FileName), zap.Error(err))
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// readRange reads keyRange, starting with txn, in as many transactions as
+// it takes to stay under FDB's 5 second limit, and hands every (sampled)
+// record to save. `n` from save is what gets counted as bytes saved.
+// Rate limits and back-off are waited out between transactions, never
+// with one open: a transaction reads at most a second's worth of the
+// limits, then the next one starts after the wait.
+func (es *ExporterSession) readRange(thread int, txn fdb.Transaction, keyRange fdb.KeyRange,
+	save func(kv fdb.KeyValue) (n int, err error)) (stat readerStat, err error) {
 
 	rangeIdentifier := fmt.Sprintf("%s-%s",
 		fdb.Printable(keyRange.Begin.FDBKey()),
 		fdb.Printable(keyRange.End.FDBKey()))
-	keysRead := int64(0)
 	keysReadInThisTxn := 0
-	bytesSaved := int64(0)
 	lastReadKey, endKey := keyRange.FDBRangeKeys()
 	batchReadLimit := 100000
+	var retry readRetry
+	var owedKeys, owedBytes int64 // read since the rate limits were last waited on
 
 Fetch:
 	for {
+		throttled := false
 		es.logger.Debug("Querying",
 			zap.Int("thread", thread),
 			zap.Int("after", keysReadInThisTxn),
@@ -190,38 +497,30 @@ Fetch:
 			// ---------------------------------------------------------
 			kv, err := it.Get()
 			if err != nil {
-				txn.Commit()
-				if errFDB, ok := err.(fdb.Error); ok && errFDB.Code == 1007 { // txn too old
-
-					// Reduce batchLimit to something smaller
+				txn, err = es.retryRead(thread, txn, err, &retry)
+				if err != nil {
+					return stat, errors.Wrapf(err, "Unable to read key range from fdb")
+				}
+				stat.retries++
+				if retry.tooOld {
+					// Reduce batchLimit to something smaller,
+					// rounded down to a multiple of 100
 					batchReadLimit = keysReadInThisTxn - keysReadInThisTxn%100
 					if batchReadLimit < 100 {
 						batchReadLimit = 100
 					}
-
-					// Round it down to a multiple of 100
 					es.logger.Info("Txn limit hit",
 						zap.Int("thread", thread),
 						zap.Int("after", keysReadInThisTxn),
 						zap.String("key", fdb.Printable(lastReadKey.FDBKey())),
 						zap.Int("new batch limit", batchReadLimit))
-
-					txn, err = es.db.CreateTransaction()
-					if err != nil {
-						return errors.Wrapf(err, "Unable to create fdb transaction")
-					}
-					keysReadInThisTxn = 0
-					err = txn.Options().SetReadYourWritesDisable()
-					if err != nil {
-						return errors.Wrapf(err, "Unable to set transaction option")
-					}
-					keyRange = fdb.KeyRange{Begin: lastReadKey, End: endKey}
-					continue Fetch
-					// continue from where we last received
 				}
-				return errors.Wrapf(err, "Unable to read key range from fdb")
+				// continue from where we last received
+				keysReadInThisTxn = 0
+				keyRange = fdb.KeyRange{Begin: lastReadKey, End: endKey}
+				continue Fetch
 			}
-			if keysReadInThisTxn == 0 && keysRead != 0 && bytes.Equal(lastReadKey.FDBKey(), kv.Key) {
+			if keysReadInThisTxn == 0 && stat.keysRead != 0 && bytes.Equal(lastReadKey.FDBKey(), kv.Key) {
 				// When retrying transactions, we don't have a way to ask for
 				// starting from the 'next' key because we don't know what the next key is.
 				// We will need to give the same key as the beginKey for next try,
@@ -233,34 +532,37 @@ Fetch:
 				continue
 			}
 
-			keysRead++
+			stat.keysRead++
 			keysReadInThisTxn++
 			if len(kv.Key) > 2048 {
 				es.logger.Warn("Invalid-key", zap.Int("keyLen", len(kv.Key)))
 			}
 			var n int
-			if es.readPercent == 100 || rand.Intn(100) <= es.readPercent {
-				if es.exportFormat == "archive" {
-					n, err = es.saveRecord(ar, kv.Key, kv.Value)
-				} else {
-					n, err = es.saveKeysPlainText(ar, kv.Key)
-				}
+			if es.sampler.keep(kv.Key) {
+				n, err = save(kv)
 				if err != nil {
-					es.logger.Error("saveRecord failed",
+					es.logger.Error("Saving record failed",
 						zap.Int("thread", thread),
 						zap.Int("after", keysReadInThisTxn),
-						zap.Int64("total", keysRead),
+						zap.Int64("total", stat.keysRead),
 						zap.Error(err))
-					return errors.Wrapf(err, "Unable to save data locally")
+					return stat, errors.Wrapf(err, "Unable to save data")
 				}
-				bytesSaved += int64(n)
+				stat.bytesSaved += int64(n)
 			} else {
-				bytesSaved += int64(len(kv.Key) + len(kv.Value))
+				stat.bytesSaved += int64(len(kv.Key) + len(kv.Value))
 			}
+			retry.attempts = 0 // made progress
 			lastReadKey = kv.Key
+			owedKeys++
+			owedBytes += int64(len(kv.Key) + len(kv.Value))
+			if es.keysLimit.exceeded(owedKeys) || es.bytesLimit.exceeded(owedBytes) {
+				throttled = true
+				break
+			}
 		}
 
-		if keysReadInThisTxn >= (batchReadLimit - 1) {
+		if throttled || keysReadInThisTxn >= (batchReadLimit-1) {
 			// Reason to compare against `batchReadLimit-1`
 			// We want to keep `keysReadInThisTxn` to be actual useful keys read
 			// When we ask for 1000 keys and skip first one, we only get 999
@@ -270,16 +572,16 @@ Fetch:
 			es.logger.Debug("Batch limit hit, starting another batch",
 				zap.Int("thread", thread),
 				zap.Int("after", keysReadInThisTxn),
-				zap.Int64("total", keysRead),
+				zap.Int64("total", stat.keysRead),
 				zap.String("key", fdb.Printable(lastReadKey.FDBKey())))
 
-			txn, err = es.db.CreateTransaction()
+			es.keysLimit.wait(owedKeys)
+			es.bytesLimit.wait(owedBytes)
+			owedKeys, owedBytes = 0, 0
+			es.pause()
+			txn, err = es.newTransaction()
 			if err != nil {
-				return errors.Wrapf(err, "Unable to create fdb transaction")
-			}
-			err = txn.Options().SetReadYourWritesDisable()
-			if err != nil {
-				return errors.Wrapf(err, "Unable to set transaction option")
+				return stat, err
 			}
 
 			keysReadInThisTxn = 0
@@ -291,35 +593,50 @@ Fetch:
 			zap.Int("thread", thread),
 			zap.Int("last-txn-read", keysReadInThisTxn),
 			zap.String("range", rangeIdentifier),
-			zap.Int64("keys", keysRead))
+			zap.Int64("keys", stat.keysRead))
 
 		break // we are really done
 	}
-	// log.Printf("NEXT: Read %d keys %d bytes", keysRead, bytesRead)
 	txn.Commit()
-	// fileName := ar.Name()
-	es.readerStatChan <- readerStat{
-		keysRead:   int64(keysRead),
-		bytesSaved: int64(bytesSaved),
-		//fileName:   fileName,
-	}
+	es.keysLimit.wait(owedKeys)
+	es.bytesLimit.wait(owedBytes)
+	es.readerStatChan <- stat
+	return stat, nil
+}
 
-	err = ar.Close()
-	if err != nil {
-		return errors.Wrapf(err, "Unable to close archive file")
-	}
-	finalizedDetails := ar.FinalizedFiles()
+// readRetry is how readRange is doing with retrying one range
+type readRetry struct {
+	attempts int  // in a row, without reading a key in between
+	tooOld   bool // the last error was transaction_too_old
+}
 
-	es.results.Lock()
-	for _, v := range finalizedDetails {
-		v.RowsWritten = keysRead
-		es.results.finalizedDetails[rangeIdentifier] = v
-		es.results.finalizedFiles[v.FileName] = true
+// retryRead decides what to do about err, returned while reading with txn.
+// Errors FDB considers retryable (by OnError, which also waits out FDB's
+// own exponential back-off) get txn back, reset and with the session's
+// options set again, up to READ_RETRY_LIMIT times in a row. Others, and
+// running out of attempts, come back as the error to fail the range on.
+func (es *ExporterSession) retryRead(thread int, txn fdb.Transaction, err error, retry *readRetry) (fdb.Transaction, error) {
 
+	errFDB, ok := errors.Cause(err).(fdb.Error)
+	if !ok {
+		return txn, err
 	}
-	// es.logger.Debug("Results so far",
-	//
-	//	zap.Any("results", es.results.finalizedDetails))
-	es.results.Unlock()
-	return nil
+	retry.tooOld = errFDB.Code == 1007
+	if retry.attempts >= READ_RETRY_LIMIT {
+		return txn, errors.Wrapf(err, "Giving up after %d retries", retry.attempts)
+	}
+	// OnError returns the error back if it is not retryable
+	if err := txn.OnError(errFDB).Get(); err != nil {
+		return txn, err
+	}
+	retry.attempts++
+	if !retry.tooOld {
+		// transaction_too_old only needs a smaller batch (see readRange)
+		es.logger.Warn("Retrying read",
+			zap.Int("thread", thread),
+			zap.Int("attempt", retry.attempts),
+			zap.Error(errFDB))
+	}
+	// OnError reset txn, which also dropped the options
+	return txn, es.setTransactionOptions(txn)
 }
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Key listings, one line per key. Unlike EXPORT_FORMAT_KEYS, these
+// never contain a raw newline, so every line is exactly one key.
+const EXPORT_FORMAT_KEYS_PRINTABLE = "keys-printable" // fdb.Printable, \xNN escapes
+const EXPORT_FORMAT_KEYS_BASE64 = "keys-base64"
+const EXPORT_FORMAT_KEYS_TUPLE = "keys-tuple" // directory <TAB> tuple <TAB> printable key
+
+// keyLineWriter writes one line per key, as encoded by `line`
+type keyLineWriter struct {
+	w    io.Writer
+	line func(key fdb.Key) string
+}
+
+func (kw keyLineWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	n, err = io.WriteString(kw.w, kw.line(kv.Key)+"\n")
+	if err != nil {
+		return n, errors.Wrapf(err, "Unable to write key")
+	}
+	return n, nil
+}
+
+func (kw keyLineWriter) Close() error {
+	return nil
+}
+
+func printableKeyLine(key fdb.Key) string {
+	return fdb.Printable(key)
+}
+
+func base64KeyLine(key fdb.Key) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// tupleKeyLine is the directory the key is in (if any), the rest of
+// the key decoded as a tuple (empty if it isn't one) and the whole
+// key, fdb.Printable. Tabs and newlines in the first column are
+// escaped, the others can't have any.
+func (es *ExporterSession) tupleKeyLine(key fdb.Key) string {
+	var dir string
+	rest := []byte(key)
+	if d, ok := es.directoryOf(key); ok {
+		dir = fdb.Printable([]byte(d.path))
+		rest = rest[len(d.prefix):]
+	}
+	var decoded string
+	if t, err := tuple.Unpack(rest); err == nil {
+		decoded = t.String()
+	}
+	return dir + "\t" + decoded + "\t" + fdb.Printable(key)
+}
+
+// directories are listed once per session, the first time they're needed
+type directories struct {
+	once sync.Once
+	list []dirPrefix // sorted by prefix
+}
+
+type dirPrefix struct {
+	path   string // a/b/c
+	prefix []byte
+}
+
+// directoryOf finds the innermost directory key is in
+func (es *ExporterSession) directoryOf(key fdb.Key) (d dirPrefix, ok bool) {
+
+	es.directories.once.Do(func() {
+		dirs, err := listDirectories(es.db, es.lockAware, nil)
+		if err != nil {
+			es.logger.Warn("Unable to list all directories, some keys will not show theirs", zap.Error(err))
+		}
+		sort.Slice(dirs, func(i, j int) bool { return bytes.Compare(dirs[i].prefix, dirs[j].prefix) < 0 })
+		es.directories.list = dirs
+	})
+	return findDirectory(es.directories.list, key)
+}
+
+// findDirectory finds the innermost directory in `list` (sorted by
+// prefix) that key is in. Prefixes only nest for directories inside a
+// partition, so a prefix of key is the last one up to key, unless a
+// sibling of it sorts in between. Then the search is repeated up to
+// where the sibling and key part.
+func findDirectory(list []dirPrefix, key []byte) (d dirPrefix, ok bool) {
+	for {
+		i := sort.Search(len(list), func(i int) bool { return bytes.Compare(list[i].prefix, key) > 0 })
+		if i == 0 {
+			return d, false
+		}
+		d = list[i-1]
+		if bytes.HasPrefix(key, d.prefix) {
+			return d, true
+		}
+		n := 0
+		for n < len(d.prefix) && d.prefix[n] == key[n] {
+			n++
+		}
+		key = key[:n]
+	}
+}
+
+// listDirectories below path, recursively, each level in a transaction
+// of its own to stay clear of FDB's 5 second limit. What was listed
+// before an error is returned with it.
+// (fdbstat.GetAllDirectories would do, but fdbstat imports this package)
+func listDirectories(db fdb.Database, lockAware bool, path []string) (dirs []dirPrefix, err error) {
+
+	var names []string
+	var level []dirPrefix
+	_, err = db.ReadTransact(func(rt fdb.ReadTransaction) (_ interface{}, err error) {
+		level = nil // on a retry
+		if lockAware {
+			err = rt.Options().SetReadLockAware()
+			if err != nil {
+				return nil, err
+			}
+		}
+		names, err = directory.List(rt, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			p := append(path[:len(path):len(path)], name)
+			ds, err := directory.Open(rt, p, nil)
+			if err != nil {
+				return nil, err
+			}
+			level = append(level, dirPrefix{path: strings.Join(p, "/"), prefix: ds.Bytes()})
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to list directory %s", strings.Join(path, "/"))
+	}
+	for i, name := range names {
+		dirs = append(dirs, level[i])
+		sub, err := listDirectories(db, lockAware, append(path[:len(path):len(path)], name))
+		dirs = append(dirs, sub...)
+		if err != nil {
+			return dirs, err
+		}
+	}
+	return dirs, nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// testDirectories is a session that knows app (prefix \x15\x01) and its
+// subdirectory app/users (prefix \x15\x02), without a database
+func testDirectories() *ExporterSession {
+	es := &ExporterSession{}
+	es.directories.once.Do(func() {})
+	es.directories.list = []dirPrefix{
+		{path: "app", prefix: []byte{0x15, 0x01}},
+		{path: "app/users", prefix: []byte{0x15, 0x02}},
+	}
+	return es
+}
+
+func TestFindDirectory(t *testing.T) {
+	// part is a partition: its subdirectories' prefixes start with its own
+	list := []dirPrefix{
+		{path: "app", prefix: []byte{0x15, 0x01}},
+		{path: "part", prefix: []byte{0x20}},
+		{path: "part/x", prefix: []byte{0x20, 0x15, 0x01}},
+		{path: "part/y", prefix: []byte{0x20, 0x15, 0x02}},
+		{path: "zoo", prefix: []byte{0x30}},
+	}
+	tests := []struct {
+		name string
+		key  []byte
+		want string // "" for none
+	}{
+		{"in a directory", []byte{0x15, 0x01, 'k'}, "app"},
+		{"before every prefix", []byte{0x15, 0x00, 'k'}, ""},
+		{"in a partition's subdirectory", []byte{0x20, 0x15, 0x01, 'k'}, "part/x"},
+		{"the prefix itself", []byte{0x20, 0x15, 0x02}, "part/y"},
+		{"in a partition, after its subdirectories", []byte{0x20, 0x15, 0x03}, "part"},
+		{"between directories", []byte{0x25}, ""},
+		{"in the last one", []byte{0x30, 'z'}, "zoo"},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := findDirectory(list, tt.key)
+			if ok != (tt.want != "") || ok && d.path != tt.want {
+				t.Errorf("findDirectory(%q) = %q, %v, want %q", tt.key, d.path, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyLines(t *testing.T) {
+	es := testDirectories()
+	userKey := append([]byte{0x15, 0x02}, tuple.Tuple{"jane", int64(7)}.Pack()...)
+	appKey := append([]byte{0x15, 0x01}, []byte("\xffraw")...)
+	tests := []struct {
+		name      string
+		key       []byte
+		printable string
+		tuple     string
+	}{
+		{"plain", []byte("hello"), "hello", "\t\thello"},
+		{"newline and tab", []byte("a\nb\tc"), "a\\x0ab\\x09c", "\t\ta\\x0ab\\x09c"},
+		{"tuple", tuple.Tuple{"k", int64(1)}.Pack(), "\\x02k\\x00\\x15\\x01",
+			"\t(\"k\", 1)\t\\x02k\\x00\\x15\\x01"},
+		{"in a subdirectory", userKey, fdb.Printable(userKey),
+			"app/users\t(\"jane\", 7)\t" + fdb.Printable(userKey)},
+		{"not a tuple in a directory", appKey, fdb.Printable(appKey),
+			"app\t\t" + fdb.Printable(appKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := printableKeyLine(tt.key); got != tt.printable {
+				t.Errorf("printableKeyLine = %q, want %q", got, tt.printable)
+			}
+			b64 := base64KeyLine(tt.key)
+			if back, err := base64.StdEncoding.DecodeString(b64); err != nil || !bytes.Equal(back, tt.key) {
+				t.Errorf("base64KeyLine = %q, decodes to %q", b64, back)
+			}
+			if got := es.tupleKeyLine(tt.key); got != tt.tuple {
+				t.Errorf("tupleKeyLine = %q, want %q", got, tt.tuple)
+			}
+		})
+	}
+}
+
+func TestKeyLineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	kw := keyLineWriter{w: &buf, line: printableKeyLine}
+	for _, key := range []string{"a", "b\nc", ""} {
+		_, err := kw.Write(fdb.KeyValue{Key: fdb.Key(key), Value: []byte("ignored")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[1] != "b\\x0ac" {
+		t.Errorf("Wrote %q, want one line per key", buf.String())
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/pkg/errors"
+)
+
+// What a redaction rule does to the values it matches
+const REDACT_DROP = "drop"         // leave the key-value pair out of the export
+const REDACT_NULL = "null"         // empty value, or a null tuple element
+const REDACT_HASH = "hash"         // hex HMAC-SHA256 with the node's salt
+const REDACT_TOKENIZE = "tokenize" // digits and letters replaced by others of the same kind, keyed by the salt
+const REDACT_REPLACE = "replace"   // a fixed value
+
+// RedactRule masks the values of keys starting with Prefix. Directory
+// rules are resolved to prefixes (one per subdirectory) by the client.
+type RedactRule struct {
+	Prefix  []byte
+	Action  string
+	Element int    // 1-based element of the value decoded as a tuple, 0 for the whole value
+	Value   []byte // for REDACT_REPLACE
+}
+
+// Redaction is applied to every record before it is written. All
+// matching rules apply, in order. Salt is the node's own secret for
+// REDACT_HASH and REDACT_TOKENIZE; nodes must share it for their
+// output to agree.
+type Redaction struct {
+	Rules []RedactRule
+	Salt  []byte
+}
+
+// ValidRedactRules returns an error for the first rule that makes no sense
+func ValidRedactRules(rules []RedactRule) error {
+	for _, r := range rules {
+		switch r.Action {
+		case REDACT_DROP, REDACT_NULL, REDACT_HASH, REDACT_TOKENIZE, REDACT_REPLACE:
+		default:
+			return errors.Errorf("Unknown redaction action %s for %s (use %s, %s, %s, %s or %s)",
+				r.Action, fdb.Printable(r.Prefix),
+				REDACT_DROP, REDACT_NULL, REDACT_HASH, REDACT_TOKENIZE, REDACT_REPLACE)
+		}
+		if r.Element < 0 {
+			return errors.Errorf("Redaction of %s: element %d, expected 1 or more (0 for the whole value)",
+				fdb.Printable(r.Prefix), r.Element)
+		}
+		if r.Action == REDACT_DROP && r.Element != 0 {
+			return errors.Errorf("Redaction of %s: %s drops whole records, not elements",
+				fdb.Printable(r.Prefix), REDACT_DROP)
+		}
+	}
+	return nil
+}
+
+// redactor applies a Redaction. A nil redactor changes nothing.
+type redactor struct {
+	rules []RedactRule
+	salt  []byte
+}
+
+func newRedactor(redaction Redaction) (rd *redactor, err error) {
+
+	if len(redaction.Rules) == 0 {
+		return nil, nil
+	}
+	err = ValidRedactRules(redaction.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range redaction.Rules {
+		if (r.Action == REDACT_HASH || r.Action == REDACT_TOKENIZE) && len(redaction.Salt) == 0 {
+			return nil, errors.Errorf("Redaction %s needs a salt, but none is configured on this node (redact.salt)", r.Action)
+		}
+	}
+	return &redactor{rules: redaction.Rules, salt: redaction.Salt}, nil
+}
+
+// apply returns kv as it is to be exported, keep is false if it is dropped.
+// An element rule on a value that isn't a tuple masks the whole value,
+// so nothing a rule was meant for gets out as is.
+func (rd *redactor) apply(kv fdb.KeyValue) (out fdb.KeyValue, keep bool) {
+
+	if rd == nil {
+		return kv, true
+	}
+	value := []byte(kv.Value)
+	var t tuple.Tuple // the value decoded, once an element rule needs it
+	decoded, isTuple, dirty := false, false, false
+	for _, r := range rd.rules {
+		if !bytes.HasPrefix(kv.Key, r.Prefix) {
+			continue
+		}
+		if r.Action == REDACT_DROP {
+			return kv, false
+		}
+		if r.Element > 0 && !decoded {
+			var err error
+			t, err = tuple.Unpack(value)
+			decoded, isTuple = true, err == nil
+		}
+		if r.Element == 0 || !isTuple {
+			if dirty {
+				value, dirty = t.Pack(), false
+			}
+			value = rd.redactBytes(r, value)
+			decoded = false
+			continue
+		}
+		if r.Element <= len(t) {
+			t[r.Element-1] = rd.redactElement(r, t[r.Element-1])
+			dirty = true
+		}
+	}
+	if dirty {
+		value = t.Pack()
+	}
+	return fdb.KeyValue{Key: kv.Key, Value: value}, true
+}
+
+func (rd *redactor) redactBytes(r RedactRule, b []byte) []byte {
+	switch r.Action {
+	case REDACT_HASH:
+		return []byte(hex.EncodeToString(rd.mac([]byte(REDACT_HASH), b)))
+	case REDACT_TOKENIZE:
+		return rd.tokenize(b)
+	case REDACT_REPLACE:
+		return r.Value
+	}
+	return []byte{} // REDACT_NULL
+}
+
+// redactElement masks one tuple element. Tokenizing keeps strings and
+// byte strings as such, and integers integers; other types are nulled.
+func (rd *redactor) redactElement(r RedactRule, e tuple.TupleElement) tuple.TupleElement {
+	switch r.Action {
+	case REDACT_HASH:
+		return hex.EncodeToString(rd.mac([]byte(REDACT_HASH), tuple.Tuple{e}.Pack()))
+	case REDACT_REPLACE:
+		return string(r.Value)
+	case REDACT_NULL:
+		return nil
+	}
+	switch v := e.(type) {
+	case string:
+		return string(rd.tokenize([]byte(v)))
+	case []byte:
+		return rd.tokenize(v)
+	case int64:
+		return rd.tokenizeInt(strconv.FormatInt(v, 10))
+	case uint64:
+		return rd.tokenizeInt(strconv.FormatUint(v, 10))
+	}
+	return nil
+}
+
+// tokenize replaces every digit by a digit, every ASCII letter by a
+// letter of the same case, and leaves everything else (punctuation,
+// non-ASCII) alone, so "jane.doe@example.com" stays e-mail shaped. The
+// same input gives the same token, for a given salt.
+func (rd *redactor) tokenize(b []byte) []byte {
+	stream := rd.keystream(b)
+	out := make([]byte, len(b))
+	for i, c := range b {
+		k := stream[i]
+		switch {
+		case c >= '0' && c <= '9':
+			out[i] = '0' + (c-'0'+k%10)%10
+		case c >= 'a' && c <= 'z':
+			out[i] = 'a' + (c-'a'+k%26)%26
+		case c >= 'A' && c <= 'Z':
+			out[i] = 'A' + (c-'A'+k%26)%26
+		default:
+			out[i] = c
+		}
+	}
+	return out
+}
+
+// tokenizeInt tokenizes the digits of a decimal integer, keeping its sign.
+// Nil if the result no longer fits.
+func (rd *redactor) tokenizeInt(digits string) tuple.TupleElement {
+	token := string(rd.tokenize([]byte(digits)))
+	if v, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseUint(token, 10, 64); err == nil {
+		return v
+	}
+	return nil
+}
+
+// keystream is len(b) bytes derived from the salt and b
+func (rd *redactor) keystream(b []byte) []byte {
+	var stream []byte
+	for block := uint32(0); len(stream) < len(b); block++ {
+		stream = append(stream, rd.mac([]byte(REDACT_TOKENIZE), b, binary.BigEndian.AppendUint32(nil, block))...)
+	}
+	return stream[:len(b)]
+}
+
+// mac is HMAC-SHA256 of parts with the salt, parts length-prefixed
+func (rd *redactor) mac(parts ...[]byte) []byte {
+	h := hmac.New(sha256.New, rd.salt)
+	for _, p := range parts {
+		h.Write(binary.AppendUvarint(nil, uint64(len(p))))
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+var testSalt = []byte("pepper")
+
+func TestNewRedactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []RedactRule
+		salt    []byte
+		wantNil bool
+		wantErr bool
+	}{
+		{"no rules", nil, nil, true, false},
+		{"replace without a salt", []RedactRule{{Prefix: []byte("a"), Action: REDACT_REPLACE}}, nil, false, false},
+		{"hash without a salt", []RedactRule{{Prefix: []byte("a"), Action: REDACT_HASH}}, nil, false, true},
+		{"tokenize without a salt", []RedactRule{{Prefix: []byte("a"), Action: REDACT_TOKENIZE}}, nil, false, true},
+		{"hash", []RedactRule{{Prefix: []byte("a"), Action: REDACT_HASH}}, testSalt, false, false},
+		{"unknown action", []RedactRule{{Prefix: []byte("a"), Action: "mask"}}, testSalt, false, true},
+		{"negative element", []RedactRule{{Prefix: []byte("a"), Action: REDACT_NULL, Element: -1}}, nil, false, true},
+		{"dropping an element", []RedactRule{{Prefix: []byte("a"), Action: REDACT_DROP, Element: 1}}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd, err := newRedactor(Redaction{Rules: tt.rules, Salt: tt.salt})
+			if (err != nil) != tt.wantErr || (rd == nil) != (tt.wantNil || tt.wantErr) {
+				t.Errorf("newRedactor = %v, %v", rd, err)
+			}
+		})
+	}
+}
+
+func TestRedactApply(t *testing.T) {
+	person := tuple.Tuple{"Jane", int64(42), "jane@example.com"}.Pack()
+	tests := []struct {
+		name  string
+		rules []RedactRule
+		key   string
+		value []byte
+		want  []byte // nil if dropped
+	}{
+		{"no match", []RedactRule{{Prefix: []byte("b"), Action: REDACT_NULL}},
+			"a1", []byte("v"), []byte("v")},
+		{"drop", []RedactRule{{Prefix: []byte("a"), Action: REDACT_DROP}},
+			"a1", []byte("v"), nil},
+		{"null", []RedactRule{{Prefix: []byte("a"), Action: REDACT_NULL}},
+			"a1", []byte("v"), []byte{}},
+		{"replace", []RedactRule{{Prefix: []byte("a"), Action: REDACT_REPLACE, Value: []byte("x")}},
+			"a1", []byte("v"), []byte("x")},
+		{"null an element", []RedactRule{{Prefix: []byte("a"), Action: REDACT_NULL, Element: 3}},
+			"a1", person, tuple.Tuple{"Jane", int64(42), nil}.Pack()},
+		{"replace an element", []RedactRule{{Prefix: []byte("a"), Action: REDACT_REPLACE, Element: 1, Value: []byte("X")}},
+			"a1", person, tuple.Tuple{"X", int64(42), "jane@example.com"}.Pack()},
+		{"element past the end", []RedactRule{{Prefix: []byte("a"), Action: REDACT_NULL, Element: 4}},
+			"a1", person, person},
+		{"element of a value that isn't a tuple", []RedactRule{{Prefix: []byte("a"), Action: REDACT_NULL, Element: 1}},
+			"a1", []byte("\x99not a tuple"), []byte{}},
+		{"rules in order", []RedactRule{
+			{Prefix: []byte("a"), Action: REDACT_NULL, Element: 1},
+			{Prefix: []byte("a1"), Action: REDACT_REPLACE, Element: 3, Value: []byte("X")},
+			{Prefix: []byte("a2"), Action: REDACT_DROP},
+		}, "a1", person, tuple.Tuple{nil, int64(42), "X"}.Pack()},
+		{"whole value after an element", []RedactRule{
+			{Prefix: []byte("a"), Action: REDACT_NULL, Element: 1},
+			{Prefix: []byte("a"), Action: REDACT_REPLACE, Value: []byte("x")},
+		}, "a1", person, []byte("x")},
+		{"element after the whole value", []RedactRule{
+			{Prefix: []byte("a"), Action: REDACT_REPLACE, Value: tuple.Tuple{"a", "b"}.Pack()},
+			{Prefix: []byte("a"), Action: REDACT_NULL, Element: 2},
+		}, "a1", person, tuple.Tuple{"a", nil}.Pack()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd, err := newRedactor(Redaction{Rules: tt.rules, Salt: testSalt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, keep := rd.apply(fdb.KeyValue{Key: fdb.Key(tt.key), Value: tt.value})
+			if !keep {
+				if tt.want != nil {
+					t.Errorf("Dropped, want %q", tt.want)
+				}
+				return
+			}
+			if tt.want == nil {
+				t.Fatalf("Kept as %q, want it dropped", out.Value)
+			}
+			if string(out.Key) != tt.key || !bytes.Equal(out.Value, tt.want) {
+				t.Errorf("apply = %q: %q, want %q", out.Key, out.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactNil(t *testing.T) {
+	var rd *redactor
+	kv := fdb.KeyValue{Key: fdb.Key("a"), Value: []byte("v")}
+	if out, keep := rd.apply(kv); !keep || string(out.Value) != "v" {
+		t.Errorf("A nil redactor changed %q to %q", kv.Value, out.Value)
+	}
+}
+
+func TestRedactHash(t *testing.T) {
+	rules := []RedactRule{{Prefix: []byte("a"), Action: REDACT_HASH}}
+	hash := func(salt []byte, value string) string {
+		rd, err := newRedactor(Redaction{Rules: rules, Salt: salt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _ := rd.apply(fdb.KeyValue{Key: fdb.Key("a"), Value: []byte(value)})
+		return string(out.Value)
+	}
+	h := hash(testSalt, "secret")
+	if !regexp.MustCompile("^[0-9a-f]{64}$").MatchString(h) {
+		t.Errorf("Hash %q is not 64 hex digits", h)
+	}
+	if hash(testSalt, "secret") != h {
+		t.Error("The same value hashed differently")
+	}
+	if hash(testSalt, "secreT") == h || hash([]byte("salt"), "secret") == h {
+		t.Error("Hash doesn't depend on the value and the salt")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	rd := &redactor{salt: testSalt}
+	shape := func(b []byte) []byte {
+		out := make([]byte, len(b))
+		for i, c := range b {
+			switch {
+			case c >= '0' && c <= '9':
+				out[i] = '9'
+			case c >= 'a' && c <= 'z':
+				out[i] = 'a'
+			case c >= 'A' && c <= 'Z':
+				out[i] = 'A'
+			default:
+				out[i] = c
+			}
+		}
+		return out
+	}
+	for _, in := range []string{
+		"",
+		"jane.doe@example.com",
+		"+1 (555) 010-9999",
+		"Zürich 8001",
+		"A much longer value, to need more than one block of the keystream: 0123456789 abcdefghij ABCDEFGHIJ",
+	} {
+		token := rd.tokenize([]byte(in))
+		if !bytes.Equal(shape(token), shape([]byte(in))) {
+			t.Errorf("tokenize(%q) = %q, not the same shape", in, token)
+		}
+		if len(in) > 8 && string(token) == in {
+			t.Errorf("tokenize(%q) left it as is", in)
+		}
+		if again := rd.tokenize([]byte(in)); !bytes.Equal(again, token) {
+			t.Errorf("tokenize(%q) = %q, then %q", in, token, again)
+		}
+		other := (&redactor{salt: []byte("salt")}).tokenize([]byte(in))
+		if len(in) > 8 && bytes.Equal(other, token) {
+			t.Errorf("tokenize(%q) is the same with another salt", in)
+		}
+	}
+}
+
+func TestTokenizeElements(t *testing.T) {
+	rd, err := newRedactor(Redaction{
+		Rules: []RedactRule{
+			{Prefix: []byte("a"), Action: REDACT_TOKENIZE, Element: 1},
+			{Prefix: []byte("a"), Action: REDACT_TOKENIZE, Element: 2},
+			{Prefix: []byte("a"), Action: REDACT_TOKENIZE, Element: 3},
+			{Prefix: []byte("a"), Action: REDACT_TOKENIZE, Element: 4},
+			{Prefix: []byte("a"), Action: REDACT_TOKENIZE, Element: 5},
+		},
+		Salt: testSalt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := tuple.Tuple{"Jane", []byte("0123"), int64(-4711), uint64(1) << 63, 1.5}
+	out, _ := rd.apply(fdb.KeyValue{Key: fdb.Key("a"), Value: in.Pack()})
+	got, err := tuple.Unpack(out.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := got[0].(string); !ok || len(s) != 4 || s == "Jane" {
+		t.Errorf("String tokenized to %#v", got[0])
+	}
+	if b, ok := got[1].([]byte); !ok || len(b) != 4 {
+		t.Errorf("Byte string tokenized to %#v", got[1])
+	}
+	if i, ok := got[2].(int64); !ok || i >= 0 || i == -4711 {
+		t.Errorf("Negative integer tokenized to %#v", got[2])
+	}
+	switch got[3].(type) {
+	case int64, uint64, nil: // nil if the token no longer fits
+	default:
+		t.Errorf("Large integer tokenized to %#v", got[3])
+	}
+	if got[4] != nil {
+		t.Errorf("Float tokenized to %#v, want nil", got[4])
+	}
+}
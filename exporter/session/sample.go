@@ -0,0 +1,108 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/pkg/errors"
+)
+
+// How a --read-percent export picks the keys it keeps
+const SAMPLE_RANDOM = "random"  // each key on its own, a different sample every run (default)
+const SAMPLE_BY_KEY = "key"     // by a hash of the key, the same sample every run
+const SAMPLE_BY_TUPLE = "tuple" // "tuple:N", by a hash of the key's first N tuple elements
+
+// Sampling is how keys are picked when reading less than 100%.
+// Hashed samples with the same Seed (and percent) keep the same keys,
+// on every node and in every run.
+type Sampling struct {
+	By   string // SAMPLE_RANDOM, SAMPLE_BY_KEY or SAMPLE_BY_TUPLE:N. "" is random
+	Seed string
+}
+
+// sampler decides for each key read whether it is exported
+type sampler struct {
+	percent  int
+	hashed   bool
+	elements int // tuple elements hashed, 0 for the whole key
+	seed     []byte
+}
+
+// ValidSampleBy returns an error if `by` is not a sampling mode.
+// Empty means the default.
+func ValidSampleBy(by string) error {
+	_, err := newSampler(100, Sampling{By: by})
+	return err
+}
+
+func newSampler(percent int, sampling Sampling) (s *sampler, err error) {
+
+	s = &sampler{percent: percent, seed: []byte(sampling.Seed)}
+	mode, n, hasN := strings.Cut(sampling.By, ":")
+	switch mode {
+	case "", SAMPLE_RANDOM:
+	case SAMPLE_BY_KEY:
+		s.hashed = true
+	case SAMPLE_BY_TUPLE:
+		s.hashed = true
+		s.elements, err = strconv.Atoi(n)
+		if !hasN || err != nil || s.elements < 1 {
+			return nil, errors.Errorf("Sampling %s needs a number of tuple elements, as in %s:2",
+				sampling.By, SAMPLE_BY_TUPLE)
+		}
+		return s, nil
+	default:
+		return nil, errors.Errorf("Unknown sampling %s (use %s, %s or %s:N)",
+			sampling.By, SAMPLE_RANDOM, SAMPLE_BY_KEY, SAMPLE_BY_TUPLE)
+	}
+	if hasN {
+		return nil, errors.Errorf("Sampling %s takes no number", mode)
+	}
+	return s, nil
+}
+
+// keep is true for the keys in the sample
+func (s *sampler) keep(key fdb.Key) bool {
+	if s.percent >= 100 {
+		return true
+	}
+	if !s.hashed {
+		return rand.Intn(100) < s.percent
+	}
+	return s.hash(key)%100 < uint64(s.percent)
+}
+
+// hash of the seed and the key, or the key's first s.elements tuple
+// elements so that all keys of an entity, e.g. ("users", 42, ...), are
+// kept or dropped together. Keys that are not tuples, or are shorter,
+// are hashed whole. A directory's prefix is one element (an integer).
+func (s *sampler) hash(key fdb.Key) uint64 {
+	part := []byte(key)
+	if s.elements > 0 {
+		if t, err := tuple.Unpack(key); err == nil && len(t) >= s.elements {
+			part = t[:s.elements].Pack()
+		}
+	}
+	h := sha256.New()
+	h.Write(s.seed)
+	h.Write([]byte{0})
+	h.Write(part)
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+func TestNewSampler(t *testing.T) {
+	for _, tt := range []struct {
+		by      string
+		wantErr bool
+	}{
+		{"", false},
+		{SAMPLE_RANDOM, false},
+		{SAMPLE_BY_KEY, false},
+		{SAMPLE_BY_TUPLE + ":1", false},
+		{SAMPLE_BY_TUPLE + ":3", false},
+		{SAMPLE_BY_TUPLE, true},
+		{SAMPLE_BY_TUPLE + ":0", true},
+		{SAMPLE_BY_TUPLE + ":x", true},
+		{SAMPLE_BY_KEY + ":2", true},
+		{"hash", true},
+	} {
+		err := ValidSampleBy(tt.by)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidSampleBy(%q) = %v, want error %v", tt.by, err, tt.wantErr)
+		}
+	}
+}
+
+func sampleKeys(n int) (keys []fdb.Key) {
+	for i := 0; i < n; i++ {
+		keys = append(keys, tuple.Tuple{"users", int64(i), "name"}.Pack())
+	}
+	return keys
+}
+
+func TestSampleSize(t *testing.T) {
+	keys := sampleKeys(20000)
+	for _, by := range []string{SAMPLE_RANDOM, SAMPLE_BY_KEY, SAMPLE_BY_TUPLE + ":2"} {
+		for _, percent := range []int{0, 1, 10, 50, 100} {
+			t.Run(fmt.Sprintf("%s %d%%", by, percent), func(t *testing.T) {
+				s, err := newSampler(percent, Sampling{By: by, Seed: "s1"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				kept := 0
+				for _, key := range keys {
+					if s.keep(key) {
+						kept++
+					}
+				}
+				// within 5 standard deviations of the binomial
+				p, n := float64(percent)/100, float64(len(keys))
+				tolerance := 5 * math.Sqrt(p*(1-p)/n) * 100
+				got := float64(kept) * 100 / n
+				if math.Abs(got-float64(percent)) > tolerance {
+					t.Errorf("Kept %.2f%% of the keys, want %d%%", got, percent)
+				}
+			})
+		}
+	}
+}
+
+func TestSampleRepeatable(t *testing.T) {
+	keys := sampleKeys(1000)
+	sample := func(by, seed string) (kept []bool) {
+		s, err := newSampler(10, Sampling{By: by, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range keys {
+			kept = append(kept, s.keep(key))
+		}
+		return kept
+	}
+	differs := func(a, b []bool) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, by := range []string{SAMPLE_BY_KEY, SAMPLE_BY_TUPLE + ":2"} {
+		if differs(sample(by, "s1"), sample(by, "s1")) {
+			t.Errorf("%s: the same seed picked different keys", by)
+		}
+		if !differs(sample(by, "s1"), sample(by, "s2")) {
+			t.Errorf("%s: different seeds picked the same keys", by)
+		}
+	}
+	if !differs(sample(SAMPLE_RANDOM, "s1"), sample(SAMPLE_RANDOM, "s1")) {
+		t.Errorf("%s: picked the same keys twice", SAMPLE_RANDOM)
+	}
+}
+
+func TestSampleByTuple(t *testing.T) {
+	s, err := newSampler(50, Sampling{By: SAMPLE_BY_TUPLE + ":2", Seed: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 100; i++ {
+		want := s.keep(tuple.Tuple{"users", i}.Pack())
+		for _, field := range []string{"name", "email", "address"} {
+			if got := s.keep(tuple.Tuple{"users", i, field}.Pack()); got != want {
+				t.Fatalf("User %d: keep %s is %v, the user's is %v", i, field, got, want)
+			}
+		}
+	}
+	// Keys that are not tuples are sampled whole, not dropped
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if s.keep(fdb.Key(fmt.Sprintf("\xffraw%d", i))) {
+			kept++
+		}
+	}
+	if kept < 400 || kept > 600 {
+		t.Errorf("Kept %d of 1000 keys that are not tuples", kept)
+	}
+}
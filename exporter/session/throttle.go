@@ -0,0 +1,236 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Throttle keeps an export from crowding out the cluster's regular work.
+// Rates are for the whole session (all reader threads), 0 is unlimited.
+type Throttle struct {
+	BytesPerSecond int64
+	KeysPerSecond  int64
+	BatchPriority  bool   // read at batch priority, ratekeeper throttles it first
+	Tag            string // transaction tag, so ratekeeper can throttle by it
+	Backoff        bool   // pause between reads while the cluster is struggling
+}
+
+const BACKOFF_CHECK_INTERVAL = 5 * time.Second
+const BACKOFF_MIN = 100 * time.Millisecond
+const BACKOFF_MAX = 5 * time.Second
+const BACKOFF_STORAGE_QUEUE = 500_000_000 // bytes, ratekeeper starts limiting at ~900 MB
+
+// limiter is a token bucket that goes into debt: a caller takes what it
+// needs and sleeps off the debt, so a big value is never stuck waiting.
+type limiter struct {
+	sync.Mutex
+	rate      float64 // per second
+	available float64
+	last      time.Time
+}
+
+// newLimiter returns nil (no limit) for rate <= 0
+func newLimiter(rate int64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{rate: float64(rate), available: float64(rate), last: time.Now()}
+}
+
+func (l *limiter) wait(n int64) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	now := time.Now()
+	l.available += now.Sub(l.last).Seconds() * l.rate
+	if l.available > l.rate {
+		l.available = l.rate // at most a second's worth of burst
+	}
+	l.last = now
+	l.available -= float64(n)
+	var debt time.Duration
+	if l.available < 0 {
+		debt = time.Duration(-l.available / l.rate * float64(time.Second))
+	}
+	l.Unlock()
+	time.Sleep(debt)
+}
+
+// exceeded is true once n is more than a second's worth
+func (l *limiter) exceeded(n int64) bool {
+	return l != nil && float64(n) >= l.rate
+}
+
+// backoff is how long readers pause before each transaction, doubled
+// while the cluster reports trouble and halved once it doesn't.
+type backoff struct {
+	sync.Mutex
+	delay time.Duration
+	stop  chan struct{} // ends watchCluster, nil if not running
+}
+
+func (es *ExporterSession) pause() {
+	es.backoff.Lock()
+	delay := es.backoff.delay
+	es.backoff.Unlock()
+	time.Sleep(delay)
+}
+
+// watchCluster adjusts es.backoff every BACKOFF_CHECK_INTERVAL
+// until stop is closed.
+func (es *ExporterSession) watchCluster(stop chan struct{}) {
+	ticker := time.NewTicker(BACKOFF_CHECK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		qos, err := getQoS(es.db, es.lockAware)
+		if err != nil {
+			es.logger.Warn("Unable to read cluster status for back-off", zap.Error(err))
+			continue
+		}
+		es.backoff.Lock()
+		before := es.backoff.delay
+		es.backoff.delay = nextDelay(before, qos.struggling())
+		after := es.backoff.delay
+		es.backoff.Unlock()
+
+		if before != after {
+			es.logger.Info("Export back-off",
+				zap.Duration("pause", after),
+				zap.String("limited-by", qos.LimitedBy),
+				zap.Int64("worst-storage-queue", qos.WorstStorageQueue))
+		}
+	}
+}
+
+// nextDelay doubles delay (to at least BACKOFF_MIN, at most BACKOFF_MAX)
+// while the cluster struggles, and halves it (down to 0) once it doesn't
+func nextDelay(delay time.Duration, struggling bool) time.Duration {
+	if struggling {
+		delay *= 2
+		if delay < BACKOFF_MIN {
+			delay = BACKOFF_MIN
+		}
+		if delay > BACKOFF_MAX {
+			delay = BACKOFF_MAX
+		}
+		return delay
+	}
+	delay /= 2
+	if delay < BACKOFF_MIN {
+		delay = 0
+	}
+	return delay
+}
+
+// qos is the part of the cluster status that tells how loaded it is.
+// (Not in fdbstat, which already imports this package.)
+type qos struct {
+	LimitedBy         string // what ratekeeper limits on, "workload" when it does not
+	WorstStorageQueue int64  // bytes
+}
+
+// struggling is true when ratekeeper limits on anything but the
+// workload, or a storage server's queue is building up
+func (q qos) struggling() bool {
+	return (q.LimitedBy != "" && q.LimitedBy != "workload") ||
+		q.WorstStorageQueue > BACKOFF_STORAGE_QUEUE
+}
+
+func getQoS(db fdb.Database, lockAware bool) (q qos, err error) {
+
+	ret, err := db.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+		if lockAware {
+			err := tr.Options().SetReadLockAware()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return tr.Get(fdb.Key("\xFF\xFF/status/json")).Get()
+	})
+	if err != nil {
+		return q, errors.Wrapf(err, "Error fetching fdb status")
+	}
+	statusb, ok := ret.([]byte)
+	if !ok {
+		return q, errors.New("Error fetching fdb status")
+	}
+	var v struct {
+		Cluster struct {
+			Qos struct {
+				PerformanceLimitedBy struct {
+					Name string `json:"name"`
+				} `json:"performance_limited_by"`
+				WorstQueueBytesStorageServer int64 `json:"worst_queue_bytes_storage_server"`
+			} `json:"qos"`
+		} `json:"cluster"`
+	}
+	err = json.Unmarshal(statusb, &v)
+	if err != nil {
+		return q, errors.Wrapf(err, "Unable to parse /status/json output")
+	}
+	q.LimitedBy = v.Cluster.Qos.PerformanceLimitedBy.Name
+	q.WorstStorageQueue = v.Cluster.Qos.WorstQueueBytesStorageServer
+	return q, nil
+}
+
+// newTransaction creates a read transaction with the session's options
+func (es *ExporterSession) newTransaction() (txn fdb.Transaction, err error) {
+
+	txn, err = es.db.CreateTransaction()
+	if err != nil {
+		return txn, errors.Wrapf(err, "Unable to create fdb transaction")
+	}
+	return txn, es.setTransactionOptions(txn)
+}
+
+// setTransactionOptions sets the session's options on txn, which is
+// new or was just reset
+func (es *ExporterSession) setTransactionOptions(txn fdb.Transaction) (err error) {
+
+	err = txn.Options().SetReadYourWritesDisable()
+	if err != nil {
+		return errors.Wrapf(err, "Unable to set transaction option")
+	}
+	if es.throttle.BatchPriority {
+		err = txn.Options().SetPriorityBatch()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to set batch priority")
+		}
+	}
+	if es.throttle.Tag != "" {
+		err = txn.Options().SetAutoThrottleTag(es.throttle.Tag)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to set transaction tag %s", es.throttle.Tag)
+		}
+	}
+	if es.lockAware {
+		err = txn.Options().SetReadLockAware()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to set lock aware")
+		}
+	}
+	return nil
+}
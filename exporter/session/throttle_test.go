@@ -0,0 +1,121 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLimiter(t *testing.T) {
+	for _, rate := range []int64{0, -1} {
+		if l := newLimiter(rate); l != nil {
+			t.Errorf("newLimiter(%d) = %+v, want no limit", rate, l)
+		}
+	}
+	var l *limiter
+	start := time.Now()
+	l.wait(1 << 40) // no limit, no wait
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("A nil limiter waited")
+	}
+}
+
+func TestLimiterDebt(t *testing.T) {
+	l := newLimiter(1000) // starts with a second's worth
+
+	start := time.Now()
+	l.wait(1000)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Waited %s within the burst", elapsed)
+	}
+	// Taking more than is left goes into debt, slept off right away
+	start = time.Now()
+	l.wait(200)
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Waited %s for 200 over the limit of 1000/s, want 200ms", elapsed)
+	}
+	if l.available > -150 {
+		t.Errorf("%.0f available after going 200 into debt", l.available)
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l := newLimiter(1000)
+	l.available = 0
+	l.last = time.Now().Add(-time.Hour) // idle for an hour
+	l.wait(0)
+	if l.available != 1000 {
+		t.Errorf("%.0f available after an hour idle, want at most a second's worth", l.available)
+	}
+	l.last = time.Now().Add(-100 * time.Millisecond)
+	l.available = 0
+	l.wait(0)
+	if l.available < 90 || l.available > 200 {
+		t.Errorf("%.0f available 100ms later, want 100", l.available)
+	}
+}
+
+func TestLimiterExceeded(t *testing.T) {
+	var none *limiter
+	if none.exceeded(1 << 40) {
+		t.Error("No limit was exceeded")
+	}
+	l := newLimiter(1000)
+	for n, want := range map[int64]bool{0: false, 999: false, 1000: true, 5000: true} {
+		if got := l.exceeded(n); got != want {
+			t.Errorf("exceeded(%d) = %v at 1000/s, want %v", n, got, want)
+		}
+	}
+}
+
+func TestNextDelay(t *testing.T) {
+	tests := []struct {
+		delay      time.Duration
+		struggling bool
+		want       time.Duration
+	}{
+		{0, true, BACKOFF_MIN},
+		{BACKOFF_MIN, true, 2 * BACKOFF_MIN},
+		{BACKOFF_MAX / 2, true, BACKOFF_MAX},
+		{BACKOFF_MAX, true, BACKOFF_MAX},
+		{BACKOFF_MAX, false, BACKOFF_MAX / 2},
+		{2 * BACKOFF_MIN, false, BACKOFF_MIN},
+		{BACKOFF_MIN, false, 0},
+		{0, false, 0},
+	}
+	for _, tt := range tests {
+		if got := nextDelay(tt.delay, tt.struggling); got != tt.want {
+			t.Errorf("nextDelay(%s, %v) = %s, want %s", tt.delay, tt.struggling, got, tt.want)
+		}
+	}
+}
+
+func TestStruggling(t *testing.T) {
+	tests := []struct {
+		q    qos
+		want bool
+	}{
+		{qos{}, false},
+		{qos{LimitedBy: "workload"}, false},
+		{qos{LimitedBy: "workload", WorstStorageQueue: BACKOFF_STORAGE_QUEUE}, false},
+		{qos{LimitedBy: "workload", WorstStorageQueue: BACKOFF_STORAGE_QUEUE + 1}, true},
+		{qos{LimitedBy: "storage_server_write_queue_size"}, true},
+		{qos{LimitedBy: "log_server_min_free_space"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.q.struggling(); got != tt.want {
+			t.Errorf("%+v struggling = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
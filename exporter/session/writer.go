@@ -0,0 +1,226 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/adobe/ferry/format"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+)
+
+const EXPORT_FORMAT_ARCHIVE = "archive" // ferry's own format, the only one import reads
+const EXPORT_FORMAT_KEYS = "keys"       // raw keys, one per line (see keys.go for safer ones)
+const EXPORT_FORMAT_JSONL = "jsonl"
+const EXPORT_FORMAT_CSV = "csv"
+const EXPORT_FORMAT_PARQUET = "parquet"
+
+const PARQUET_ROW_GROUP_ROWS = 100_000
+
+// RecordWriter writes the records of one range to one export file.
+// rangeReader picks the implementation by the session's export format.
+// Write returns the bytes it counts as saved. Close must be called to
+// finish the file, but does not close the underlying writer.
+type RecordWriter interface {
+	Write(kv fdb.KeyValue) (n int, err error)
+	Close() error
+}
+
+// fileExtension of the export files of exportFormat
+func fileExtension(exportFormat string) string {
+	switch exportFormat {
+	case EXPORT_FORMAT_JSONL, EXPORT_FORMAT_CSV, EXPORT_FORMAT_PARQUET:
+		return "." + exportFormat
+	}
+	return ".records"
+}
+
+func validExportFormat(exportFormat string) bool {
+	switch exportFormat {
+	case EXPORT_FORMAT_ARCHIVE, EXPORT_FORMAT_KEYS, EXPORT_FORMAT_KEYS_PRINTABLE,
+		EXPORT_FORMAT_KEYS_BASE64, EXPORT_FORMAT_KEYS_TUPLE, EXPORT_FORMAT_JSONL,
+		EXPORT_FORMAT_CSV, EXPORT_FORMAT_PARQUET:
+		return true
+	}
+	return false
+}
+
+func (es *ExporterSession) newRecordWriter(w io.Writer, header format.Header) (RecordWriter, error) {
+	switch es.exportFormat {
+	case EXPORT_FORMAT_ARCHIVE:
+		return archiveWriter{format.NewRecordWriter(w, header)}, nil
+	case EXPORT_FORMAT_KEYS:
+		return keysWriter{es: es, w: w}, nil
+	case EXPORT_FORMAT_KEYS_PRINTABLE:
+		return keyLineWriter{w: w, line: printableKeyLine}, nil
+	case EXPORT_FORMAT_KEYS_BASE64:
+		return keyLineWriter{w: w, line: base64KeyLine}, nil
+	case EXPORT_FORMAT_KEYS_TUPLE:
+		return keyLineWriter{w: w, line: es.tupleKeyLine}, nil
+	case EXPORT_FORMAT_JSONL:
+		return jsonlWriter{json.NewEncoder(w)}, nil
+	case EXPORT_FORMAT_CSV:
+		return newCSVWriter(w)
+	case EXPORT_FORMAT_PARQUET:
+		return newParquetWriter(w), nil
+	}
+	return nil, errors.Errorf("Unknown export format %s", es.exportFormat)
+}
+
+// keyTuple is the key decoded as a tuple, or "" if it isn't one
+func keyTuple(key fdb.Key) string {
+	t, err := tuple.Unpack(key)
+	if err != nil {
+		return ""
+	}
+	return t.String()
+}
+
+// rangeEnder is a RecordWriter whose files can say where they end,
+// when that is before the end of their range (the range was rolled
+// over to another file).
+type rangeEnder interface {
+	SetEnd(end []byte)
+}
+
+type archiveWriter struct {
+	records *format.RecordWriter
+}
+
+func (aw archiveWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	return aw.records.Write(kv.Key, kv.Value)
+}
+
+func (aw archiveWriter) SetEnd(end []byte) {
+	aw.records.SetEnd(end)
+}
+
+func (aw archiveWriter) Close() error {
+	return aw.records.Close()
+}
+
+type keysWriter struct {
+	es *ExporterSession
+	w  io.Writer
+}
+
+func (kw keysWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	return kw.es.saveKeysPlainText(kw.w, kv.Key)
+}
+
+func (kw keysWriter) Close() error {
+	return nil
+}
+
+// jsonlRecord is one line of a jsonl export. Key and value are
+// base64 encoded, as they are arbitrary bytes.
+type jsonlRecord struct {
+	Key      []byte `json:"key"`
+	Value    []byte `json:"value"`
+	KeyTuple string `json:"key_tuple,omitempty"`
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (jw jsonlWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	err = jw.enc.Encode(jsonlRecord{Key: kv.Key, Value: kv.Value, KeyTuple: keyTuple(kv.Key)})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Unable to write jsonl record")
+	}
+	return len(kv.Key) + len(kv.Value), nil
+}
+
+func (jw jsonlWriter) Close() error {
+	return nil
+}
+
+// csvWriter writes a header line, then key,value,key_tuple per
+// record. Key and value are base64 encoded.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (RecordWriter, error) {
+	cw := csvWriter{csv.NewWriter(w)}
+	err := cw.w.Write([]string{"key", "value", "key_tuple"})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to write csv header")
+	}
+	return cw, nil
+}
+
+func (cw csvWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	err = cw.w.Write([]string{
+		base64.StdEncoding.EncodeToString(kv.Key),
+		base64.StdEncoding.EncodeToString(kv.Value),
+		keyTuple(kv.Key),
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Unable to write csv record")
+	}
+	return len(kv.Key) + len(kv.Value), nil
+}
+
+func (cw csvWriter) Close() error {
+	cw.w.Flush()
+	return errors.Wrapf(cw.w.Error(), "Unable to write csv records")
+}
+
+// parquetRecord is one row of a parquet export. key_tuple is null
+// for keys that aren't tuples.
+type parquetRecord struct {
+	Key      []byte `parquet:"key"`
+	Value    []byte `parquet:"value"`
+	KeyTuple string `parquet:"key_tuple,optional"`
+}
+
+// parquetWriter keeps at most PARQUET_ROW_GROUP_ROWS rows in
+// memory, the rest is already written out as row groups.
+type parquetWriter struct {
+	w    *parquet.GenericWriter[parquetRecord]
+	rows *int
+}
+
+func newParquetWriter(w io.Writer) RecordWriter {
+	return parquetWriter{
+		w:    parquet.NewGenericWriter[parquetRecord](w, parquet.Compression(&parquet.Snappy)),
+		rows: new(int),
+	}
+}
+
+func (pw parquetWriter) Write(kv fdb.KeyValue) (n int, err error) {
+	_, err = pw.w.Write([]parquetRecord{{Key: kv.Key, Value: kv.Value, KeyTuple: keyTuple(kv.Key)}})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Unable to write parquet record")
+	}
+	*pw.rows++
+	if *pw.rows%PARQUET_ROW_GROUP_ROWS == 0 {
+		err = pw.w.Flush()
+		if err != nil {
+			return 0, errors.Wrapf(err, "Unable to write parquet row group")
+		}
+	}
+	return len(kv.Key) + len(kv.Value), nil
+}
+
+func (pw parquetWriter) Close() error {
+	return errors.Wrapf(pw.w.Close(), "Unable to finish parquet file")
+}
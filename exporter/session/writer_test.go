@@ -0,0 +1,185 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/adobe/ferry/format"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/parquet-go/parquet-go"
+)
+
+// writerRecords are a tuple key, a key that isn't a tuple (with bytes
+// that aren't valid UTF-8) and an empty value
+var writerRecords = []fdb.KeyValue{
+	{Key: tuple.Tuple{"users", int64(42)}.Pack(), Value: []byte("jane")},
+	{Key: fdb.Key("\xff\x00raw"), Value: []byte{0x80, 0x81}},
+	{Key: tuple.Tuple{"empty"}.Pack(), Value: []byte{}},
+}
+
+// writeRecords writes records in exportFormat and returns the file
+func writeRecords(t *testing.T, exportFormat string, records []fdb.KeyValue) []byte {
+	t.Helper()
+	es := &ExporterSession{exportFormat: exportFormat}
+	var buf bytes.Buffer
+	rw, err := es.newRecordWriter(&buf, format.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range records {
+		n, err := rw.Write(kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(kv.Key)+len(kv.Value) {
+			t.Errorf("Write counted %d bytes, want %d", n, len(kv.Key)+len(kv.Value))
+		}
+	}
+	err = rw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func wantKeyTuple(key fdb.Key) string {
+	t, err := tuple.Unpack(key)
+	if err != nil {
+		return ""
+	}
+	return t.String()
+}
+
+func TestJSONLWriter(t *testing.T) {
+	b := writeRecords(t, EXPORT_FORMAT_JSONL, writerRecords)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	i := 0
+	for ; scanner.Scan(); i++ {
+		var fields map[string]interface{}
+		err := json.Unmarshal(scanner.Bytes(), &fields)
+		if err != nil {
+			t.Fatalf("Line %d: %v", i+1, err)
+		}
+		var r jsonlRecord
+		err = json.Unmarshal(scanner.Bytes(), &r)
+		if err != nil {
+			t.Fatalf("Line %d: %v", i+1, err)
+		}
+		kv := writerRecords[i]
+		if fields["key"] != base64.StdEncoding.EncodeToString(kv.Key) {
+			t.Errorf("Line %d: key %v is not base64 of %q", i+1, fields["key"], kv.Key)
+		}
+		if !bytes.Equal(r.Key, kv.Key) || !bytes.Equal(r.Value, kv.Value) {
+			t.Errorf("Line %d: read back %q=%q, want %q=%q", i+1, r.Key, r.Value, kv.Key, kv.Value)
+		}
+		_, hasTuple := fields["key_tuple"]
+		if want := wantKeyTuple(kv.Key); r.KeyTuple != want || hasTuple != (want != "") {
+			t.Errorf("Line %d: key_tuple %v, want %q", i+1, fields["key_tuple"], want)
+		}
+	}
+	if i != len(writerRecords) {
+		t.Errorf("%d lines, want %d", i, len(writerRecords))
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	b := writeRecords(t, EXPORT_FORMAT_CSV, writerRecords)
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(writerRecords)+1 {
+		t.Fatalf("%d rows, want a header and %d records", len(rows), len(writerRecords))
+	}
+	if header := rows[0]; len(header) != 3 || header[0] != "key" || header[1] != "value" || header[2] != "key_tuple" {
+		t.Errorf("Header is %q", header)
+	}
+	for i, row := range rows[1:] {
+		kv := writerRecords[i]
+		key, kerr := base64.StdEncoding.DecodeString(row[0])
+		value, verr := base64.StdEncoding.DecodeString(row[1])
+		if kerr != nil || verr != nil || !bytes.Equal(key, kv.Key) || !bytes.Equal(value, kv.Value) {
+			t.Errorf("Row %d is %q, want base64 of %q and %q", i+1, row, kv.Key, kv.Value)
+		}
+		if want := wantKeyTuple(kv.Key); row[2] != want {
+			t.Errorf("Row %d: key_tuple %q, want %q", i+1, row[2], want)
+		}
+	}
+}
+
+func TestCSVWriterHeaderOnly(t *testing.T) {
+	b := writeRecords(t, EXPORT_FORMAT_CSV, nil)
+	if string(b) != "key,value,key_tuple\n" {
+		t.Errorf("Wrote %q without records", b)
+	}
+}
+
+// parquetRow reads parquetRecord back, with a null key_tuple as nil
+type parquetRow struct {
+	Key      []byte  `parquet:"key"`
+	Value    []byte  `parquet:"value"`
+	KeyTuple *string `parquet:"key_tuple,optional"`
+}
+
+func TestParquetWriter(t *testing.T) {
+	b := writeRecords(t, EXPORT_FORMAT_PARQUET, writerRecords)
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(writerRecords) {
+		t.Fatalf("%d rows, want %d", len(rows), len(writerRecords))
+	}
+	for i, row := range rows {
+		kv := writerRecords[i]
+		if !bytes.Equal(row.Key, kv.Key) || !bytes.Equal(row.Value, kv.Value) {
+			t.Errorf("Row %d is %q=%q, want %q=%q", i, row.Key, row.Value, kv.Key, kv.Value)
+		}
+		want := wantKeyTuple(kv.Key)
+		switch {
+		case want == "" && row.KeyTuple != nil:
+			t.Errorf("Row %d: key_tuple %q, want null", i, *row.KeyTuple)
+		case want != "" && (row.KeyTuple == nil || *row.KeyTuple != want):
+			t.Errorf("Row %d: key_tuple %v, want %q", i, row.KeyTuple, want)
+		}
+	}
+}
+
+func TestParquetRowGroups(t *testing.T) {
+	var records []fdb.KeyValue
+	for i := 0; i < PARQUET_ROW_GROUP_ROWS*2+1; i++ {
+		records = append(records, fdb.KeyValue{Key: tuple.Tuple{int64(i)}.Pack(), Value: []byte{byte(i)}})
+	}
+	b := writeRecords(t, EXPORT_FORMAT_PARQUET, records)
+	f, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != int64(len(records)) {
+		t.Errorf("%d rows, want %d", f.NumRows(), len(records))
+	}
+	var sizes []int64
+	for _, rg := range f.RowGroups() {
+		sizes = append(sizes, rg.NumRows())
+	}
+	if len(sizes) != 3 || sizes[0] != PARQUET_ROW_GROUP_ROWS || sizes[1] != PARQUET_ROW_GROUP_ROWS || sizes[2] != 1 {
+		t.Errorf("Row groups of %v rows, want a new one every %d", sizes, PARQUET_ROW_GROUP_ROWS)
+	}
+}
@@ -43,7 +43,7 @@ func NewHashableKeyRange(kr fdb.KeyRange) HashableKeyRange {
 func NewKeyRangeStats(kr fdb.KeyRange, size int64) KeyRangeStats {
 	s := KeyRangeStats{}
 	s.Begin = append(s.Begin, kr.Begin.FDBKey()...)
-	s.End = append(s.Begin, kr.End.FDBKey()...)
+	s.End = append(s.End, kr.End.FDBKey()...)
 	s.Size = size
 	return s
 }
@@ -238,13 +238,11 @@ func (s *Surveyor) CalculateDBSizeAsync(pmap *finder.PartitionMap) (sizeByRange
 
 func (s *Surveyor) CalculateRowCount(pmap *finder.PartitionMap, readerThreads int) (totalRows int64, err error) {
 
-	es, err := session.NewSession(s.db,
-		"",
-		readerThreads,
-		false,
-		s.logger,
-		100,
-		"archive")
+	es, err := session.NewSession(s.db, "", s.logger, session.Options{
+		ReaderThreads: readerThreads,
+		ReadPercent:   100,
+		ExportFormat:  session.EXPORT_FORMAT_ARCHIVE,
+	})
 	if err != nil {
 		s.logger.Warn("Failed to create a session ID", zap.Error(err))
 		return 0, errors.Wrap(err, "Failed to create a session ID")
@@ -3,6 +3,7 @@ package fdbstat
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
@@ -80,3 +81,21 @@ func GetAllNodes(db fdb.Database) (hosts []string, err error) {
 	}
 	return hosts, err
 }
+
+// GetClusterDescription returns the `description:ID` part of the
+// connection string the database handle is connected with.
+func GetClusterDescription(db fdb.Database) (description string, err error) {
+
+	ret, err := db.ReadTransact(func(rt fdb.ReadTransaction) (v interface{}, err error) {
+		return rt.Get(fdb.Key("\xFF\xFF/connection_string")).Get()
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Error fetching connection string")
+	}
+	connStr, ok := ret.([]byte)
+	if !ok {
+		return "", errors.New("Error fetching connection string")
+	}
+	description, _, _ = strings.Cut(string(connStr), "@")
+	return description, nil
+}
@@ -13,7 +13,9 @@ governing permissions and limitations under the License.
 package finder
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
@@ -198,3 +200,49 @@ func (exp *Finder) GetBoundaryKeys() (boundaryKeys []fdb.Key, err error) {
 	exp.logger.Debug("All keys", zap.String("keys", fmt.Sprintf("%+v", boundaryKeys)))
 	return boundaryKeys, nil
 }
+
+// Exclude returns a copy of the partition map with `done` ranges cut out.
+// Ranges partially covered by `done` are trimmed (or split) and keep
+// their original hosts.
+func (pmap *PartitionMap) Exclude(done []fdb.KeyRange) *PartitionMap {
+
+	sorted := append([]fdb.KeyRange(nil), done...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Begin.FDBKey(), sorted[j].Begin.FDBKey()) < 0
+	})
+
+	var ranges []RangeLocation
+	var nodes = map[string]StorageGroup{}
+	for _, rl := range pmap.Ranges {
+		cur, end := rl.Krange.Begin.FDBKey(), rl.Krange.End.FDBKey()
+		var pieces []fdb.KeyRange
+		for _, d := range sorted {
+			dBegin, dEnd := d.Begin.FDBKey(), d.End.FDBKey()
+			if bytes.Compare(dEnd, cur) <= 0 {
+				continue // entirely before what is left of this range
+			}
+			if bytes.Compare(dBegin, end) >= 0 {
+				break // this and all following are past this range
+			}
+			if bytes.Compare(dBegin, cur) > 0 {
+				pieces = append(pieces, fdb.KeyRange{Begin: cur, End: dBegin})
+			}
+			cur = dEnd
+			if bytes.Compare(cur, end) >= 0 {
+				break
+			}
+		}
+		if bytes.Compare(cur, end) < 0 {
+			pieces = append(pieces, fdb.KeyRange{Begin: cur, End: end})
+		}
+		for _, p := range pieces {
+			ranges = append(ranges, RangeLocation{Krange: p, Hosts: rl.Hosts})
+			for _, host := range rl.Hosts {
+				node := nodes[host]
+				node.kranges = append(nodes[host].kranges, p)
+				nodes[host] = node
+			}
+		}
+	}
+	return &PartitionMap{Ranges: ranges, Nodes: nodes}
+}
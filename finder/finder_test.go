@@ -0,0 +1,73 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package finder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// kr is a key range, "" as end is the end of the keyspace
+func kr(begin, end string) fdb.KeyRange {
+	if end == "" {
+		end = "\xff"
+	}
+	return fdb.KeyRange{Begin: fdb.Key(begin), End: fdb.Key(end)}
+}
+
+func pmapString(pmap *PartitionMap) string {
+	var s []string
+	for _, rl := range pmap.Ranges {
+		s = append(s, fmt.Sprintf("%s-%s@%s", fdb.Printable(rl.Krange.Begin.FDBKey()),
+			fdb.Printable(rl.Krange.End.FDBKey()), strings.Join(rl.Hosts, ",")))
+	}
+	return strings.Join(s, " ")
+}
+
+func testPmap() *PartitionMap {
+	return &PartitionMap{Ranges: []RangeLocation{
+		{Krange: kr("", "d"), Hosts: []string{"h1", "h2"}},
+		{Krange: kr("d", "k"), Hosts: []string{"h2", "h3"}},
+		{Krange: kr("k", ""), Hosts: []string{"h3", "h1"}},
+	}}
+}
+
+func TestExclude(t *testing.T) {
+	tests := []struct {
+		name string
+		done []fdb.KeyRange
+		want string
+	}{
+		{"nothing done", nil, "-d@h1,h2 d-k@h2,h3 k-\\xff@h3,h1"},
+		{"a whole range", []fdb.KeyRange{kr("d", "k")}, "-d@h1,h2 k-\\xff@h3,h1"},
+		{"the start of a range", []fdb.KeyRange{kr("d", "f")}, "-d@h1,h2 f-k@h2,h3 k-\\xff@h3,h1"},
+		{"the end of a range", []fdb.KeyRange{kr("f", "k")}, "-d@h1,h2 d-f@h2,h3 k-\\xff@h3,h1"},
+		{"the middle, splitting it", []fdb.KeyRange{kr("f", "g")}, "-d@h1,h2 d-f@h2,h3 g-k@h2,h3 k-\\xff@h3,h1"},
+		{"across ranges", []fdb.KeyRange{kr("c", "m")}, "-c@h1,h2 m-\\xff@h3,h1"},
+		{"unsorted, several in one range", []fdb.KeyRange{kr("h", "i"), kr("e", "f")},
+			"-d@h1,h2 d-e@h2,h3 f-h@h2,h3 i-k@h2,h3 k-\\xff@h3,h1"},
+		{"overlapping", []fdb.KeyRange{kr("e", "g"), kr("f", "h")}, "-d@h1,h2 d-e@h2,h3 h-k@h2,h3 k-\\xff@h3,h1"},
+		{"everything", []fdb.KeyRange{kr("", "")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pmapString(testPmap().Exclude(tt.done))
+			if got != tt.want {
+				t.Errorf("Exclude = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package finder
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// GetLocationsInRanges is GetBoundaryKeys + GetLocations limited to `scope`.
+// Only shards overlapping scope are resolved, and the resulting ranges
+// are clipped to it.
+func (exp *Finder) GetLocationsInRanges(scope []fdb.KeyRange, skipHostResolution bool) (pmap *PartitionMap, err error) {
+
+	pmap = &PartitionMap{Nodes: map[string]StorageGroup{}}
+	for _, krange := range MergeRanges(scope) {
+		bKeys, err := exp.GetBoundaryKeysInRange(krange)
+		if err != nil {
+			return nil, err
+		}
+		partial, err := exp.GetLocations(bKeys, skipHostResolution)
+		if err != nil {
+			return nil, err
+		}
+		partial = partial.Clip([]fdb.KeyRange{krange})
+		pmap.Ranges = append(pmap.Ranges, partial.Ranges...)
+		for host, sg := range partial.Nodes {
+			node := pmap.Nodes[host]
+			node.kranges = append(node.kranges, sg.kranges...)
+			pmap.Nodes[host] = node
+		}
+	}
+	return pmap, nil
+}
+
+// GetBoundaryKeysInRange returns the first key of every shard overlapping
+// krange. krange.Begin stands in for the start of the first shard, which
+// usually begins before it.
+func (exp *Finder) GetBoundaryKeysInRange(krange fdb.KeyRange) (boundaryKeys []fdb.Key, err error) {
+
+	beginKey := krange.Begin.FDBKey()
+	boundaryKeys = append(boundaryKeys, beginKey)
+
+	for {
+		bKeys, err := exp.db.LocalityGetBoundaryKeys(fdb.KeyRange{Begin: beginKey, End: krange.End},
+			1000, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error querying LocalityGetBoundaryKeys")
+		}
+		added := 0
+		for _, k := range bKeys {
+			// results are inclusive of beginKey, which we already have
+			if bytes.Compare(k, beginKey) > 0 {
+				boundaryKeys = append(boundaryKeys, k)
+				added++
+			}
+		}
+		if added == 0 {
+			break
+		}
+		beginKey = boundaryKeys[len(boundaryKeys)-1]
+	}
+	exp.logger.Debug("Boundary keys in range",
+		zap.String("begin", fdb.Printable(krange.Begin.FDBKey())),
+		zap.String("end", fdb.Printable(krange.End.FDBKey())),
+		zap.Int("count", len(boundaryKeys)))
+	return boundaryKeys, nil
+}
+
+// Clip returns a copy of the partition map with every range cut down to
+// its overlap with `scope`. Ranges outside scope are dropped.
+func (pmap *PartitionMap) Clip(scope []fdb.KeyRange) *PartitionMap {
+
+	var ranges []RangeLocation
+	var nodes = map[string]StorageGroup{}
+	for _, rl := range pmap.Ranges {
+		for _, s := range scope {
+			begin, end := rl.Krange.Begin.FDBKey(), rl.Krange.End.FDBKey()
+			if bytes.Compare(s.Begin.FDBKey(), begin) > 0 {
+				begin = s.Begin.FDBKey()
+			}
+			if bytes.Compare(s.End.FDBKey(), end) < 0 {
+				end = s.End.FDBKey()
+			}
+			if bytes.Compare(begin, end) >= 0 {
+				continue
+			}
+			p := fdb.KeyRange{Begin: begin, End: end}
+			ranges = append(ranges, RangeLocation{Krange: p, Hosts: rl.Hosts})
+			for _, host := range rl.Hosts {
+				node := nodes[host]
+				node.kranges = append(nodes[host].kranges, p)
+				nodes[host] = node
+			}
+		}
+	}
+	return &PartitionMap{Ranges: ranges, Nodes: nodes}
+}
+
+// MergeRanges sorts ranges and merges the ones that overlap or touch,
+// so no key is exported twice.
+func MergeRanges(kranges []fdb.KeyRange) (merged []fdb.KeyRange) {
+
+	sorted := append([]fdb.KeyRange(nil), kranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Begin.FDBKey(), sorted[j].Begin.FDBKey()) < 0
+	})
+	for _, kr := range sorted {
+		if bytes.Compare(kr.Begin.FDBKey(), kr.End.FDBKey()) >= 0 {
+			continue // empty
+		}
+		if n := len(merged); n > 0 && bytes.Compare(kr.Begin.FDBKey(), merged[n-1].End.FDBKey()) <= 0 {
+			if bytes.Compare(kr.End.FDBKey(), merged[n-1].End.FDBKey()) > 0 {
+				merged[n-1].End = kr.End
+			}
+			continue
+		}
+		merged = append(merged, kr)
+	}
+	return merged
+}
+
+// DirectoryRanges returns the key ranges holding the contents of the
+// directory at `path` and, recursively, of all its subdirectories
+// (the directory layer gives each its own unrelated prefix).
+func DirectoryRanges(db fdb.Database, path []string) (kranges []fdb.KeyRange, err error) {
+
+	dir, err := directory.Open(db, path, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to open directory %+v", path)
+	}
+	bk, ek := dir.FDBRangeKeys()
+	kranges = append(kranges, fdb.KeyRange{Begin: bk, End: ek})
+
+	children, err := dir.List(db, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to list directory %+v", path)
+	}
+	for _, child := range children {
+		sub, err := DirectoryRanges(db, append(append([]string(nil), path...), child))
+		if err != nil {
+			return nil, err
+		}
+		kranges = append(kranges, sub...)
+	}
+	return kranges, nil
+}
+
+// ParsePrintable reverses fdb.Printable, so keys can be given on the
+// command line the way ferry prints them (e.g. `\x15*\x00`).
+func ParsePrintable(s string) (key fdb.Key, err error) {
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			key = append(key, s[i])
+			continue
+		}
+		switch {
+		case i+1 < len(s) && s[i+1] == '\\':
+			key = append(key, '\\')
+			i++
+		case i+3 < len(s) && s[i+1] == 'x':
+			b, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Bad escape in %s at %d", s, i)
+			}
+			key = append(key, byte(b))
+			i += 3
+		default:
+			return nil, errors.Errorf("Bad escape in %s at %d", s, i)
+		}
+	}
+	return key, nil
+}
+
+// SplitRange cuts krange into pieces of about chunkSize bytes each, at
+// the split points FDB suggests. A range smaller than that comes back whole.
+func SplitRange(db fdb.Database, krange fdb.KeyRange, chunkSize int64) (pieces []fdb.KeyRange, err error) {
+
+	splitPoints, err := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		return rtr.GetRangeSplitPoints(krange, chunkSize).Get()
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get split points of %s - %s",
+			fdb.Printable(krange.Begin.FDBKey()), fdb.Printable(krange.End.FDBKey()))
+	}
+	return splitAt(krange, splitPoints.([]fdb.Key)), nil
+}
+
+// splitAt cuts krange at split points that start with krange.Begin and
+// end with krange.End, as FDB returns them
+func splitAt(krange fdb.KeyRange, keys []fdb.Key) (pieces []fdb.KeyRange) {
+	for i := 1; i < len(keys); i++ {
+		if bytes.Compare(keys[i-1], keys[i]) < 0 {
+			pieces = append(pieces, fdb.KeyRange{Begin: keys[i-1], End: keys[i]})
+		}
+	}
+	if len(pieces) == 0 {
+		return []fdb.KeyRange{krange}
+	}
+	return pieces
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package finder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+func rangesString(kranges []fdb.KeyRange) string {
+	var s []string
+	for _, r := range kranges {
+		s = append(s, fmt.Sprintf("%s-%s", fdb.Printable(r.Begin.FDBKey()), fdb.Printable(r.End.FDBKey())))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []fdb.KeyRange
+		want string
+	}{
+		{"none", nil, ""},
+		{"one", []fdb.KeyRange{kr("a", "b")}, "a-b"},
+		{"sorted", []fdb.KeyRange{kr("c", "d"), kr("a", "b")}, "a-b c-d"},
+		{"overlapping", []fdb.KeyRange{kr("a", "c"), kr("b", "d")}, "a-d"},
+		{"touching", []fdb.KeyRange{kr("a", "b"), kr("b", "c")}, "a-c"},
+		{"contained", []fdb.KeyRange{kr("a", "z"), kr("c", "d")}, "a-z"},
+		{"empty dropped", []fdb.KeyRange{kr("b", "b"), kr("d", "c"), kr("e", "f")}, "e-f"},
+		{"chain", []fdb.KeyRange{kr("e", "g"), kr("a", "c"), kr("b", "f"), kr("x", "y")}, "a-g x-y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rangesString(MergeRanges(tt.in))
+			if got != tt.want {
+				t.Errorf("MergeRanges = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope []fdb.KeyRange
+		want  string
+	}{
+		{"everything", []fdb.KeyRange{kr("", "")}, "-d@h1,h2 d-k@h2,h3 k-\\xff@h3,h1"},
+		{"inside one range", []fdb.KeyRange{kr("e", "f")}, "e-f@h2,h3"},
+		{"across ranges", []fdb.KeyRange{kr("c", "m")}, "c-d@h1,h2 d-k@h2,h3 k-m@h3,h1"},
+		{"on a boundary", []fdb.KeyRange{kr("d", "k")}, "d-k@h2,h3"},
+		{"several", []fdb.KeyRange{kr("a", "b"), kr("x", "y")}, "a-b@h1,h2 x-y@h3,h1"},
+		{"nothing", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clipped := testPmap().Clip(tt.scope)
+			got := pmapString(clipped)
+			if got != tt.want {
+				t.Errorf("Clip = %q, want %q", got, tt.want)
+			}
+			for host, node := range clipped.Nodes {
+				for _, r := range node.kranges {
+					if !strings.Contains(got, rangesString([]fdb.KeyRange{r})) {
+						t.Errorf("Host %s has range %s, not in the clipped map", host, rangesString([]fdb.KeyRange{r}))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitAt(t *testing.T) {
+	keys := func(ks ...string) (keys []fdb.Key) {
+		for _, k := range ks {
+			keys = append(keys, fdb.Key(k))
+		}
+		return keys
+	}
+	tests := []struct {
+		name   string
+		points []fdb.Key
+		want   string
+	}{
+		{"no split points", nil, "b-m"},
+		{"just the ends", keys("b", "m"), "b-m"},
+		{"one split", keys("b", "f", "m"), "b-f f-m"},
+		{"several", keys("b", "d", "f", "h", "m"), "b-d d-f f-h h-m"},
+		{"repeated point", keys("b", "f", "f", "m"), "b-f f-m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rangesString(splitAt(kr("b", "m"), tt.points))
+			if got != tt.want {
+				t.Errorf("splitAt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
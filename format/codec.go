@@ -0,0 +1,165 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pkg/errors"
+)
+
+// Compression of whole export files. The codec is told by the file
+// name's extension, so readers need nothing else to pick the decoder.
+// The archive library decompresses ".lz4" files itself when reading.
+const CODEC_NONE = "none"
+const CODEC_LZ4 = "lz4"
+const CODEC_ZSTD = "zstd"
+const CODEC_GZIP = "gzip"
+
+const ZSTD_DEFAULT_LEVEL = 3
+const GZIP_DEFAULT_LEVEL = gzip.DefaultCompression
+
+// Codec is a compression codec and level, as in --compress=zstd:9
+type Codec struct {
+	Name  string
+	Level int // 0 for codecs without levels
+}
+
+// ParseCodec reads "none", "lz4", "zstd[:1-22]" or "gzip[:1-9]".
+// "" and "false" are none, "true" is lz4 (from when --compress
+// was a bool).
+func ParseCodec(s string) (c Codec, err error) {
+
+	name, level, hasLevel := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	switch name {
+	case "", "false", CODEC_NONE:
+		c.Name = CODEC_NONE
+	case "true", CODEC_LZ4:
+		c.Name = CODEC_LZ4
+	case CODEC_ZSTD:
+		c = Codec{Name: CODEC_ZSTD, Level: ZSTD_DEFAULT_LEVEL}
+	case CODEC_GZIP, "gz":
+		c = Codec{Name: CODEC_GZIP, Level: GZIP_DEFAULT_LEVEL}
+	default:
+		return c, errors.Errorf("Unknown compression %s, expected none|lz4|zstd[:level]|gzip[:level]", s)
+	}
+	if !hasLevel {
+		return c, nil
+	}
+	min, max := 0, 0
+	switch c.Name {
+	case CODEC_ZSTD:
+		min, max = 1, 22
+	case CODEC_GZIP:
+		min, max = gzip.BestSpeed, gzip.BestCompression
+	}
+	c.Level, err = strconv.Atoi(level)
+	if err != nil || max == 0 || c.Level < min || c.Level > max {
+		return c, errors.Errorf("Invalid compression level in %s", s)
+	}
+	return c, nil
+}
+
+// String is what goes into the manifest, and back into ParseCodec
+func (c Codec) String() string {
+	if c.Level == 0 || c.Level == GZIP_DEFAULT_LEVEL && c.Name == CODEC_GZIP {
+		return c.Name
+	}
+	return fmt.Sprintf("%s:%d", c.Name, c.Level)
+}
+
+// Extension to append to the file name, "" for none
+func (c Codec) Extension() string {
+	switch c.Name {
+	case CODEC_LZ4:
+		return ".lz4"
+	case CODEC_ZSTD:
+		return ".zst"
+	case CODEC_GZIP:
+		return ".gz"
+	}
+	return ""
+}
+
+// NewWriter compresses into w. Close flushes, but does not close w.
+// For none it writes through as is.
+func (c Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c.Name {
+	case CODEC_LZ4:
+		return lz4.NewWriter(w), nil
+	case CODEC_ZSTD:
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.Level)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to start zstd compression")
+		}
+		return zw, nil
+	case CODEC_GZIP:
+		gw, err := gzip.NewWriterLevel(w, c.Level)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to start gzip compression")
+		}
+		return gw, nil
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newDecoder picks the decoders by fileName's extension, outermost
+// (last) first. Closing it does not close r. key is needed for
+// encrypted files only.
+func newDecoder(fileName string, r io.Reader, key *Key) (io.ReadCloser, error) {
+	name := strings.ToLower(fileName)
+	if strings.HasSuffix(name, ENCRYPTED_EXTENSION) {
+		if key == nil {
+			return nil, errors.Errorf("%s is encrypted, but no encryption key is configured", fileName)
+		}
+		var err error
+		r, err = key.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to decrypt %s", fileName)
+		}
+		name = strings.TrimSuffix(name, ENCRYPTED_EXTENSION)
+		if strings.HasSuffix(name, ".lz4") {
+			// The archive library only saw ".enc", so this is ours to do
+			return io.NopCloser(lz4.NewReader(r)), nil
+		}
+	}
+	switch {
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to start zstd decompression")
+		}
+		return zr.IOReadCloser(), nil
+	case strings.HasSuffix(name, ".gz"):
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to start gzip decompression")
+		}
+		return gr, nil
+	}
+	return io.NopCloser(r), nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+func TestParseCodec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Codec
+		str     string // String(), "" if in is invalid
+		wantErr bool
+	}{
+		{in: "", want: Codec{Name: CODEC_NONE}, str: "none"},
+		{in: "false", want: Codec{Name: CODEC_NONE}, str: "none"},
+		{in: "none", want: Codec{Name: CODEC_NONE}, str: "none"},
+		{in: "true", want: Codec{Name: CODEC_LZ4}, str: "lz4"},
+		{in: " LZ4 ", want: Codec{Name: CODEC_LZ4}, str: "lz4"},
+		{in: "zstd", want: Codec{Name: CODEC_ZSTD, Level: ZSTD_DEFAULT_LEVEL}, str: "zstd:3"},
+		{in: "zstd:1", want: Codec{Name: CODEC_ZSTD, Level: 1}, str: "zstd:1"},
+		{in: "zstd:22", want: Codec{Name: CODEC_ZSTD, Level: 22}, str: "zstd:22"},
+		{in: "gzip", want: Codec{Name: CODEC_GZIP, Level: GZIP_DEFAULT_LEVEL}, str: "gzip"},
+		{in: "gz:9", want: Codec{Name: CODEC_GZIP, Level: 9}, str: "gzip:9"},
+		{in: "zstd:0", wantErr: true},
+		{in: "zstd:23", wantErr: true},
+		{in: "zstd:fast", wantErr: true},
+		{in: "gzip:10", wantErr: true},
+		{in: "lz4:1", wantErr: true},
+		{in: "none:1", wantErr: true},
+		{in: "snappy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCodec(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCodec(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCodec(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCodec(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Errorf("String() = %q, want %q", got.String(), tt.str)
+			}
+			again, err := ParseCodec(got.String())
+			if err != nil || again != got {
+				t.Errorf("ParseCodec(String()) = %+v, %v, want %+v", again, err, got)
+			}
+		})
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("ferry ferry ferry "), 10000)
+	for _, name := range []string{"none", "lz4", "zstd:1", "zstd:19", "gzip:1", "gzip"} {
+		t.Run(name, func(t *testing.T) {
+			c, err := ParseCodec(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			w, err := c.NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = w.Write(data)
+			if err == nil {
+				err = w.Close()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Name != CODEC_NONE && buf.Len() >= len(data) {
+				t.Errorf("%d bytes compressed to %d", len(data), buf.Len())
+			}
+			var r io.Reader
+			if c.Name == CODEC_LZ4 {
+				r = lz4.NewReader(&buf) // the archive library's to decode, for unencrypted files
+			} else {
+				r, err = newDecoder("fdb_1_2.records"+c.Extension(), &buf, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Read back %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Encrypted files are AES-256-GCM, in chunks so they can be streamed:
+//
+//	[ magic "FERRYENC" ] [ version byte ]
+//	[ key-id-length uvarint ] [ key-id ]
+//	[ wrapped-key-length uvarint ] [ wrapped data key ]  (length 0: no envelope)
+//	[ nonce-prefix 8 bytes ]
+//	[ chunk ] . . .
+//
+//	chunk = [ length uint32 LE, high bit set on the last chunk ] [ sealed ]
+//
+// sealed is at most ENCRYPTION_CHUNK_SIZE bytes of (compressed) content plus
+// the GCM tag. Its nonce is nonce-prefix + chunk number (uint32 BE), and the
+// header plus a last-chunk flag are authenticated with it, so chunks can't
+// be reordered, swapped between files, or dropped off the end.
+//
+// With an envelope, every file has its own random data key, sealed with the
+// master key ([ nonce 12 bytes ] [ sealed key ]). Without one, the key
+// itself encrypts the file, and the random nonce prefix keeps files apart.
+const ENCRYPTION_MAGIC = "FERRYENC"
+const ENCRYPTION_VERSION = 1
+const ENCRYPTION_CHUNK_SIZE = 64 << 10
+const ENCRYPTED_EXTENSION = ".enc"
+
+const KEY_SIZE = 32 // AES-256
+const lastChunk = 1 << 31
+
+// Key is the key material of one node (or client), from .ferry.yaml.
+// Only its ID ever leaves the host, e.g. into the manifest.
+type Key struct {
+	ID       string
+	secret   []byte
+	envelope bool // secret is a master key, wrapping a new data key per file
+}
+
+// LoadKey reads a 32-byte key from keyFile (raw, hex or base64), or takes
+// masterKey (hex or base64) to wrap per-file data keys with. Neither set
+// is no encryption: nil, nil. keyID defaults to a hash of the key, so a
+// wrong key is told apart from a corrupted file.
+func LoadKey(keyFile, masterKey, keyID string) (key *Key, err error) {
+
+	var secret []byte
+	envelope := false
+	switch {
+	case keyFile != "" && masterKey != "":
+		return nil, errors.New("Set either an encryption key file or a master key, not both")
+	case keyFile != "":
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to read encryption key file %s", keyFile)
+		}
+		secret, err = decodeSecret(b, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid encryption key in %s", keyFile)
+		}
+	case masterKey != "":
+		secret, err = decodeSecret([]byte(masterKey), false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid encryption master key")
+		}
+		envelope = true
+	default:
+		return nil, nil
+	}
+	if keyID == "" {
+		sum := sha256.Sum256(secret)
+		keyID = hex.EncodeToString(sum[:8])
+	}
+	return &Key{ID: keyID, secret: secret, envelope: envelope}, nil
+}
+
+// decodeSecret takes a 256-bit key as 64 hex or 44 base64 characters, or
+// (with raw) as KEY_SIZE bytes as they are. Hex is tried first. Text that
+// decodes to any other size is rejected, unless raw bytes are allowed and
+// it is KEY_SIZE bytes long: a raw key can happen to be valid hex or base64.
+func decodeSecret(b []byte, raw bool) ([]byte, error) {
+	s := strings.TrimSpace(string(b))
+	encoding := ""
+	k, err := hex.DecodeString(s)
+	if err == nil {
+		encoding = "hex"
+	} else if k, err = base64.StdEncoding.DecodeString(s); err == nil {
+		encoding = "base64"
+	}
+	switch {
+	case encoding != "" && len(k) == KEY_SIZE:
+		return k, nil
+	case raw && len(b) == KEY_SIZE:
+		return b, nil
+	case encoding != "":
+		return nil, errors.Errorf("Key is %d bits (as %s), expected %d", len(k)*8, encoding, KEY_SIZE*8)
+	}
+	if raw {
+		return nil, errors.Errorf("Expected %d bytes, raw, hex or base64", KEY_SIZE)
+	}
+	return nil, errors.Errorf("Expected %d bytes, hex or base64", KEY_SIZE)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to set up AES")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to set up AES-GCM")
+	}
+	return gcm, nil
+}
+
+type encryptWriter struct {
+	w      io.Writer
+	gcm    cipher.AEAD
+	header []byte // authenticated with every chunk
+	prefix []byte
+	chunk  uint32
+	buf    []byte
+	sealed []byte
+	aad    []byte
+	closed bool
+	lenbuf [4]byte
+	nonce  [12]byte
+}
+
+// NewWriter encrypts into w. Close writes the last chunk, but does not
+// close w.
+func (k *Key) NewWriter(w io.Writer) (io.WriteCloser, error) {
+
+	dataKey := k.secret
+	var wrapped []byte
+	if k.envelope {
+		dataKey = make([]byte, KEY_SIZE)
+		_, err := rand.Read(dataKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to generate a data key")
+		}
+		master, err := newGCM(k.secret)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, master.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to generate a nonce")
+		}
+		wrapped = master.Seal(nonce, nonce, dataKey, []byte(k.ID))
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, 8)
+	_, err = rand.Read(prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to generate a nonce")
+	}
+
+	header := append([]byte(ENCRYPTION_MAGIC), ENCRYPTION_VERSION)
+	header = binary.AppendUvarint(header, uint64(len(k.ID)))
+	header = append(header, k.ID...)
+	header = binary.AppendUvarint(header, uint64(len(wrapped)))
+	header = append(header, wrapped...)
+	header = append(header, prefix...)
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to write encryption header")
+	}
+	return &encryptWriter{
+		w:      w,
+		gcm:    gcm,
+		header: header,
+		prefix: prefix,
+		buf:    make([]byte, 0, ENCRYPTION_CHUNK_SIZE),
+	}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(ew.buf) == ENCRYPTION_CHUNK_SIZE {
+			err = ew.seal(false)
+			if err != nil {
+				return n, err
+			}
+		}
+		m := copy(ew.buf[len(ew.buf):ENCRYPTION_CHUNK_SIZE], p)
+		ew.buf = ew.buf[:len(ew.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close seals what is buffered as the last chunk, empty if need be
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.seal(true)
+}
+
+func (ew *encryptWriter) seal(last bool) (err error) {
+	if ew.chunk == lastChunk {
+		return errors.New("Too many chunks for one encrypted file")
+	}
+	copy(ew.nonce[:8], ew.prefix)
+	binary.BigEndian.PutUint32(ew.nonce[8:], ew.chunk)
+	ew.aad = chunkAAD(ew.aad[:0], ew.header, last)
+	ew.sealed = ew.gcm.Seal(ew.sealed[:0], ew.nonce[:], ew.buf, ew.aad)
+
+	length := uint32(len(ew.sealed))
+	if last {
+		length |= lastChunk
+	}
+	binary.LittleEndian.PutUint32(ew.lenbuf[:], length)
+	_, err = ew.w.Write(ew.lenbuf[:])
+	if err == nil {
+		_, err = ew.w.Write(ew.sealed)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write encrypted chunk")
+	}
+	ew.chunk++
+	ew.buf = ew.buf[:0]
+	return nil
+}
+
+func chunkAAD(aad, header []byte, last bool) []byte {
+	aad = append(aad, header...)
+	if last {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+type decryptReader struct {
+	r      *bufio.Reader
+	gcm    cipher.AEAD
+	header []byte
+	prefix []byte
+	chunk  uint32
+	plain  []byte // decrypted, not yet read
+	sealed []byte
+	aad    []byte
+	done   bool
+}
+
+// NewReader decrypts what NewWriter wrote. Errors say whether the file
+// is not encrypted, was encrypted with another key, or is corrupted.
+func (k *Key) NewReader(r io.Reader) (io.Reader, error) {
+
+	br := bufio.NewReader(r)
+	var header bytes.Buffer
+	tr := io.TeeReader(br, &header)
+	fixed := make([]byte, len(ENCRYPTION_MAGIC)+1)
+	_, err := io.ReadFull(tr, fixed)
+	if err != nil || string(fixed[:len(ENCRYPTION_MAGIC)]) != ENCRYPTION_MAGIC {
+		return nil, errors.New("Not an encrypted ferry file")
+	}
+	if fixed[len(ENCRYPTION_MAGIC)] != ENCRYPTION_VERSION {
+		return nil, errors.Errorf("Unsupported encryption version %d", fixed[len(ENCRYPTION_MAGIC)])
+	}
+	keyID, err := readLengthPrefixed(tr)
+	if err != nil {
+		return nil, err
+	}
+	if string(keyID) != k.ID {
+		return nil, errors.Errorf("File was encrypted with key %s, not %s", keyID, k.ID)
+	}
+	wrapped, err := readLengthPrefixed(tr)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, 8)
+	_, err = io.ReadFull(tr, prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Truncated encryption header")
+	}
+
+	dataKey := k.secret
+	if len(wrapped) > 0 {
+		master, err := newGCM(k.secret)
+		if err != nil {
+			return nil, err
+		}
+		if len(wrapped) < master.NonceSize() {
+			return nil, errors.New("Corrupted wrapped data key")
+		}
+		dataKey, err = master.Open(nil, wrapped[:master.NonceSize()], wrapped[master.NonceSize():], keyID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to unwrap data key (wrong master key?)")
+		}
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: br, gcm: gcm, header: header.Bytes(), prefix: prefix}, nil
+}
+
+func readLengthPrefixed(r io.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(byteReader{r})
+	if err != nil || n > 1<<10 {
+		return nil, errors.New("Corrupted encryption header")
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Truncated encryption header")
+	}
+	return b, nil
+}
+
+type byteReader struct {
+	io.Reader
+}
+
+func (br byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(br.Reader, b[:])
+	return b[0], err
+}
+
+func (dr *decryptReader) Read(p []byte) (n int, err error) {
+	for len(dr.plain) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		err = dr.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+func (dr *decryptReader) open() error {
+	var lenbuf [4]byte
+	_, err := io.ReadFull(dr.r, lenbuf[:])
+	if err != nil {
+		return errors.Errorf("Encrypted file truncated after chunk %d", dr.chunk)
+	}
+	length := binary.LittleEndian.Uint32(lenbuf[:])
+	last := length&lastChunk != 0
+	length &^= lastChunk
+	if length > ENCRYPTION_CHUNK_SIZE+uint32(dr.gcm.Overhead()) {
+		return errors.Errorf("Corrupted encrypted chunk %d", dr.chunk)
+	}
+	if cap(dr.sealed) < int(length) {
+		dr.sealed = make([]byte, length)
+	}
+	dr.sealed = dr.sealed[:length]
+	_, err = io.ReadFull(dr.r, dr.sealed)
+	if err != nil {
+		return errors.Errorf("Encrypted file truncated in chunk %d", dr.chunk)
+	}
+
+	var nonce [12]byte
+	copy(nonce[:8], dr.prefix)
+	binary.BigEndian.PutUint32(nonce[8:], dr.chunk)
+	dr.aad = chunkAAD(dr.aad[:0], dr.header, last)
+	dr.plain, err = dr.gcm.Open(dr.sealed[:0], nonce[:], dr.sealed, dr.aad)
+	if err != nil {
+		return errors.Errorf("Encrypted chunk %d failed authentication", dr.chunk)
+	}
+	dr.chunk++
+	if last {
+		dr.done = true
+		if _, err := dr.r.ReadByte(); err != io.EOF {
+			return errors.New("Unexpected data after the last encrypted chunk")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSecret = func() (b []byte) {
+	for i := 0; i < KEY_SIZE; i++ {
+		b = append(b, byte(i*7))
+	}
+	return b
+}()
+
+func testKey(t *testing.T, envelope bool, id string) *Key {
+	t.Helper()
+	var key *Key
+	var err error
+	if envelope {
+		key, err = LoadKey("", hex.EncodeToString(testSecret), id)
+	} else {
+		key, err = LoadKey(writeKeyFile(t, testSecret), "", id)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeKeyFile(t *testing.T, b []byte) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "export.key")
+	err := os.WriteFile(name, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func encrypt(t *testing.T, key *Key, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := key.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(key *Key, b []byte) ([]byte, error) {
+	r, err := key.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope bool
+		size     int
+	}{
+		{"empty", false, 0},
+		{"one chunk", false, 1000},
+		{"exactly one chunk", false, ENCRYPTION_CHUNK_SIZE},
+		{"several chunks", false, 3*ENCRYPTION_CHUNK_SIZE + 17},
+		{"wrapped data key", true, 2*ENCRYPTION_CHUNK_SIZE + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := testKey(t, tt.envelope, "")
+			data := bytes.Repeat([]byte("0123456789"), tt.size/10+1)[:tt.size]
+			b := encrypt(t, key, data)
+			if tt.size > 0 && bytes.Contains(b, data[:min(tt.size, 100)]) {
+				t.Error("Plain text found in the encrypted file")
+			}
+			got, err := decrypt(key, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("Decrypted %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestEncryptTamper(t *testing.T) {
+	key := testKey(t, false, "k1")
+	good := encrypt(t, key, bytes.Repeat([]byte("x"), 2*ENCRYPTION_CHUNK_SIZE+100))
+	headerLen := len(ENCRYPTION_MAGIC) + 1 + 1 + len("k1") + 1 + 8
+	firstChunk := headerLen + 4 + ENCRYPTION_CHUNK_SIZE + 16 // with its length and GCM tag
+
+	tests := []struct {
+		name   string
+		tamper func(b []byte) []byte
+		key    *Key
+		want   string
+	}{
+		{"flipped bit in a chunk", func(b []byte) []byte {
+			b[headerLen+100] ^= 0x01
+			return b
+		}, key, "failed authentication"},
+		{"flipped bit in the header", func(b []byte) []byte {
+			b[headerLen-1] ^= 0x01
+			return b
+		}, key, "failed authentication"},
+		{"chunks swapped", func(b []byte) []byte {
+			second := b[firstChunk : 2*firstChunk-headerLen]
+			swapped := append(append(append([]byte(nil), b[:headerLen]...), second...), b[headerLen:firstChunk]...)
+			return append(swapped, b[2*firstChunk-headerLen:]...)
+		}, key, "failed authentication"},
+		{"truncated after a chunk", func(b []byte) []byte {
+			return b[:firstChunk]
+		}, key, "truncated after chunk 1"},
+		{"truncated in a chunk", func(b []byte) []byte {
+			return b[:firstChunk+100]
+		}, key, "truncated in chunk 1"},
+		{"data after the last chunk", func(b []byte) []byte {
+			return append(b, 0)
+		}, key, "after the last encrypted chunk"},
+		{"not encrypted", func(b []byte) []byte {
+			return []byte("FDBFERRY plain archive")
+		}, key, "Not an encrypted ferry file"},
+		{"other key", func(b []byte) []byte {
+			return b
+		}, &Key{ID: "k2", secret: testSecret}, "encrypted with key k1, not k2"},
+		{"wrong secret under the same ID", func(b []byte) []byte {
+			return b
+		}, &Key{ID: "k1", secret: bytes.Repeat([]byte{1}, KEY_SIZE)}, "failed authentication"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decrypt(tt.key, tt.tamper(append([]byte(nil), good...)))
+			if err == nil {
+				t.Fatal("Decrypted without error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrongMasterKey(t *testing.T) {
+	b := encrypt(t, testKey(t, true, "k1"), []byte("secret"))
+	other, err := LoadKey("", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KEY_SIZE)), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = decrypt(other, b)
+	if err == nil || !strings.Contains(err.Error(), "Unable to unwrap data key") {
+		t.Errorf("Error %v, want a failure to unwrap the data key", err)
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	hex64 := hex.EncodeToString(testSecret)
+	base64of24 := base64.StdEncoding.EncodeToString(testSecret[:24]) // 32 characters
+	tests := []struct {
+		name string
+		in   []byte
+		raw  bool
+		want []byte // nil for an error
+	}{
+		{"hex", []byte(hex64), false, testSecret},
+		{"hex with newline", []byte(hex64 + "\n"), true, testSecret},
+		{"base64", []byte(base64.StdEncoding.EncodeToString(testSecret)), false, testSecret},
+		{"raw", testSecret, true, testSecret},
+		{"raw not allowed", testSecret, false, nil},
+		{"128-bit hex", []byte(hex64[:32]), false, nil},
+		{"raw that is valid hex", []byte(hex64[:32]), true, []byte(hex64[:32])},
+		{"192-bit base64", []byte(base64of24), false, nil},
+		{"raw that is valid base64", []byte(base64of24), true, []byte(base64of24)},
+		{"short raw", testSecret[:16], true, nil},
+		{"passphrase", []byte("correct horse battery staple"), true, nil},
+		{"empty", nil, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSecret(tt.in, tt.raw)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("decodeSecret(%q) = %x, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decodeSecret(%q) = %x, want %x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package format reads and writes ferry's binary "archive" export files.
+// RecordWriter is what the exporter uses; RecordReader (see Open) is what
+// import and verify use, and is meant for any Go program consuming exports.
+//
+// Version 2 (written today):
+//
+//	[ magic "FDBFERRY" ] [ version uint16 LE ]
+//	[ header-length uvarint ] [ header ] [ crc32c(header) uint32 LE ]
+//	[ block ] . . . [ 0x00 ] [ trailer ]
+//
+//	header = [ len uvarint ][ begin-key ] [ len uvarint ][ end-key ]
+//	         [ read-version varint ] [ create-time unix-nano varint ]
+//	         [ block-size uvarint ]  (fields may be appended later)
+//	block  = [ length uvarint ] [ records ] [ crc32c(records) uint32 LE ]
+//	record = [ key-length uvarint ] [ value-length uvarint ] [ key ] [ value ]
+//	trailer = [ length uvarint ] [ len uvarint ][ end-key ] [ crc32c uint32 LE ]
+//
+// A zero-length block marks the end of the file, so a truncated file
+// is an error rather than a short read.
+//
+// The trailer is optional. It is written when the file ends before the
+// header's end key (the exporter rolled over to a new file), and holds
+// the key the file actually ends at. Readers that stop at the end marker
+// never see it.
+//
+// Version 1 files are a bare sequence of records, each prefixed with a
+// uint32 LE holding key-length (higher 14 bits) and value-length (lower 18).
+package format
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const FORMAT_V1 = 1
+const FORMAT_V2 = 2
+const CURRENT_VERSION = FORMAT_V2
+
+const MAGIC = "FDBFERRY"
+
+const DEFAULT_BLOCK_SIZE = 64 << 10 // Records are buffered and checksummed in blocks of (at least) this size
+const MAX_BLOCK_LEN = 256 << 20     // Sanity limit while reading. A single record larger than this is not an FDB value
+
+const V1_MAX_KEY_LEN = (1 << 14) - 1   // Max 14 bits for its length
+const V1_MAX_VALUE_LEN = (1 << 18) - 1 // Max 18 bits for its length
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Header describes an archive file. Version 1 files have no header;
+// reading one gives a Header with only Version set.
+type Header struct {
+	Version     int
+	Begin       []byte    // Key range the file was exported from
+	End         []byte    //
+	ReadVersion int64     // FDB read version the export started at
+	CreateTime  time.Time //
+	BlockSize   int       // Target size of each checksummed block
+}
+
+func (h Header) marshal() []byte {
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(h.Begin)))
+	b = append(b, h.Begin...)
+	b = binary.AppendUvarint(b, uint64(len(h.End)))
+	b = append(b, h.End...)
+	b = binary.AppendVarint(b, h.ReadVersion)
+	b = binary.AppendVarint(b, h.CreateTime.UnixNano())
+	b = binary.AppendUvarint(b, uint64(h.BlockSize))
+	return b
+}
+
+func (h *Header) unmarshal(b []byte) (err error) {
+	h.Begin, b, err = readBytes(b)
+	if err != nil {
+		return errors.Wrapf(err, "Bad begin key in header")
+	}
+	h.End, b, err = readBytes(b)
+	if err != nil {
+		return errors.Wrapf(err, "Bad end key in header")
+	}
+	var n int
+	h.ReadVersion, n = binary.Varint(b)
+	if n <= 0 {
+		return errors.New("Bad read version in header")
+	}
+	b = b[n:]
+	nanos, n := binary.Varint(b)
+	if n <= 0 {
+		return errors.New("Bad create time in header")
+	}
+	h.CreateTime = time.Unix(0, nanos)
+	b = b[n:]
+	blockSize, n := binary.Uvarint(b)
+	if n <= 0 {
+		return errors.New("Bad block size in header")
+	}
+	h.BlockSize = int(blockSize)
+	// Anything after this was added by a later minor revision. Ignore.
+	return nil
+}
+
+// readBytes splits a uvarint length-prefixed byte string off the front of b.
+func readBytes(b []byte) (value, rest []byte, err error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, nil, errors.New("Bad length prefix")
+	}
+	b = b[n:]
+	if uint64(len(b)) < l {
+		return nil, nil, errors.Errorf("Length %d exceeds the %d bytes left", l, len(b))
+	}
+	return b[:l], b[l:], nil
+}
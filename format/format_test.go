@@ -0,0 +1,186 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type kv struct {
+	key, value []byte
+}
+
+func records(n, valueSize int) (kvs []kv) {
+	for i := 0; i < n; i++ {
+		kvs = append(kvs, kv{
+			key:   []byte(fmt.Sprintf("key-%06d", i)),
+			value: bytes.Repeat([]byte{byte(i)}, valueSize),
+		})
+	}
+	return kvs
+}
+
+func writeFile(t *testing.T, header Header, kvs []kv, end []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	rw := NewRecordWriter(&buf, header)
+	for _, r := range kvs {
+		_, err := rw.Write(r.key, r.value)
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if end != nil {
+		rw.SetEnd(end)
+	}
+	err := rw.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readFile(b []byte) (rr *RecordReader, kvs []kv, err error) {
+	rr, err = NewRecordReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	for rr.Next() {
+		kvs = append(kvs, kv{key: rr.Key(), value: rr.Value()})
+	}
+	return rr, kvs, rr.Err()
+}
+
+func TestRoundTrip(t *testing.T) {
+	header := Header{
+		Begin:       []byte("a"),
+		End:         []byte("z"),
+		ReadVersion: 123456789,
+		CreateTime:  time.Unix(0, 1700000000123456789),
+	}
+	tests := []struct {
+		name      string
+		blockSize int
+		kvs       []kv
+		end       []byte // SetEnd, nil for none
+		wantEnd   string
+	}{
+		{"one record", 0, records(1, 10), nil, "z"},
+		{"empty value", 0, []kv{{key: []byte("k"), value: nil}}, nil, "z"},
+		{"many blocks", 100, records(1000, 50), nil, "z"},
+		{"record larger than a block", 16, records(3, 1000), nil, "z"},
+		{"value over the v1 limit", 0, records(2, V1_MAX_VALUE_LEN+1), nil, "z"},
+		{"trailer", 100, records(100, 10), []byte("m"), "m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := header
+			h.BlockSize = tt.blockSize
+			rr, got, err := readFile(writeFile(t, h, tt.kvs, tt.end))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if len(got) != len(tt.kvs) || rr.Count() != int64(len(tt.kvs)) {
+				t.Fatalf("Read %d records (count %d), want %d", len(got), rr.Count(), len(tt.kvs))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i].key, tt.kvs[i].key) || !bytes.Equal(got[i].value, tt.kvs[i].value) {
+					t.Fatalf("Record %d is %q, want %q", i, got[i].key, tt.kvs[i].key)
+				}
+			}
+			hdr := rr.Header()
+			if hdr.Version != CURRENT_VERSION || string(hdr.Begin) != "a" || string(hdr.End) != "z" ||
+				hdr.ReadVersion != header.ReadVersion || !hdr.CreateTime.Equal(header.CreateTime) {
+				t.Errorf("Header is %+v", hdr)
+			}
+			if string(rr.End()) != tt.wantEnd {
+				t.Errorf("End is %q, want %q", rr.End(), tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestNoRecordsNoFile(t *testing.T) {
+	b := writeFile(t, Header{Begin: []byte("a"), End: []byte("b")}, nil, nil)
+	if len(b) != 0 {
+		t.Errorf("Wrote %d bytes without records", len(b))
+	}
+}
+
+func TestReadV1(t *testing.T) {
+	var b []byte
+	for _, r := range records(3, 5) {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(r.key))<<18|uint32(len(r.value)))
+		b = append(b, r.key...)
+		b = append(b, r.value...)
+	}
+	rr, got, err := readFile(b)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if rr.Header().Version != FORMAT_V1 || len(got) != 3 || string(got[2].key) != "key-000002" {
+		t.Errorf("Read version %d, %d records", rr.Header().Version, len(got))
+	}
+}
+
+func TestCorruption(t *testing.T) {
+	good := writeFile(t, Header{Begin: []byte("a"), End: []byte("z"), BlockSize: 64},
+		records(20, 10), []byte("q"))
+	headerLen := len(MAGIC) + 2 + 1 + len(Header{Begin: []byte("a"), End: []byte("z"), BlockSize: 64}.marshal()) + 4
+
+	tests := []struct {
+		name    string
+		corrupt func(b []byte) []byte
+		want    string // in the error
+	}{
+		{"flipped bit in a block", func(b []byte) []byte {
+			b[headerLen+10] ^= 0x01
+			return b
+		}, "Checksum mismatch"},
+		{"flipped bit in the header", func(b []byte) []byte {
+			b[len(MAGIC)+4] ^= 0x01
+			return b
+		}, "Corrupted archive header"},
+		{"flipped bit in the trailer", func(b []byte) []byte {
+			b[len(b)-6] ^= 0x01
+			return b
+		}, "Corrupted archive trailer"},
+		{"truncated in a block", func(b []byte) []byte {
+			return b[:headerLen+30]
+		}, "unexpected EOF"},
+		{"truncated before the end marker", func(b []byte) []byte {
+			return b[:len(b)-len("q")-6-1]
+		}, "truncated"},
+		{"unknown version", func(b []byte) []byte {
+			b[len(MAGIC)] = 99
+			return b
+		}, "version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.corrupt(append([]byte(nil), good...))
+			_, _, err := readFile(b)
+			if err == nil {
+				t.Fatal("Corrupted file read without error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
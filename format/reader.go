@@ -0,0 +1,360 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/adobe/blackhole/lib/archive"
+	"github.com/pkg/errors"
+)
+
+// RecordReader reads archive files of any supported version.
+//
+// Either call Read until it returns io.EOF, or iterate:
+//
+//	rr, err := format.Open(url, 0)
+//	...
+//	defer rr.Close()
+//	for rr.Next() {
+//		use(rr.Key(), rr.Value())
+//	}
+//	if err := rr.Err(); err != nil {
+//		...
+//	}
+type RecordReader struct {
+	r      *offsetReader
+	header Header
+	end    []byte      // from the trailer, if any
+	closer []io.Closer // set by Open, innermost first
+
+	key, value []byte // current record, for Next
+	err        error  //
+	count      int64  // records read so far
+
+	block    []byte // records of the current block not yet returned
+	blockNum int    // 1-based, for error messages
+	done     bool
+}
+
+// NewRecordReader reads the header (if any) from r. Files that do not
+// start with MAGIC are read as version 1.
+func NewRecordReader(r io.Reader) (rr *RecordReader, err error) {
+	rr = &RecordReader{r: &offsetReader{br: bufio.NewReader(r)}}
+
+	magic, err := rr.r.br.Peek(len(MAGIC))
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "Unable to read archive header")
+	}
+	if !bytes.Equal(magic, []byte(MAGIC)) {
+		rr.header.Version = FORMAT_V1
+		return rr, nil
+	}
+	err = rr.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+type openOptions struct {
+	key *Key
+}
+
+type OpenOption func(*openOptions)
+
+// Decrypt .enc files with key. A nil key is fine for unencrypted files.
+func Decrypt(key *Key) OpenOption {
+	return func(o *openOptions) {
+		o.key = key
+	}
+}
+
+// Open opens an archive file (local path or any URL the archive library
+// supports, e.g. s3:// or az://) for reading. Compressed files are
+// decompressed by their extension (.lz4, .zst, .gz), encrypted ones (.enc)
+// decrypted with the key given by Decrypt. Close releases the file.
+func Open(fileName string, bufferSize int, options ...OpenOption) (rr *RecordReader, err error) {
+	var opts openOptions
+	for _, o := range options {
+		o(&opts)
+	}
+	ar, err := archive.OpenArchive(fileName, bufferSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to open export file %s", fileName)
+	}
+	dec, err := newDecoder(fileName, ar, opts.key)
+	if err != nil {
+		ar.Close()
+		return nil, errors.Wrapf(err, "Unable to read export file %s", fileName)
+	}
+	rr, err = NewRecordReader(dec)
+	if err != nil {
+		dec.Close()
+		ar.Close()
+		return nil, errors.Wrapf(err, "Unable to read export file %s", fileName)
+	}
+	rr.closer = []io.Closer{dec, ar}
+	return rr, nil
+}
+
+func (rr *RecordReader) Header() Header {
+	return rr.header
+}
+
+// End is the key the file's range ends at: the header's End, unless
+// the trailer says the file was cut short (see RecordWriter.SetEnd).
+// The trailer is only read at the end of the file, so call End after
+// reading all records.
+func (rr *RecordReader) End() []byte {
+	if rr.end != nil {
+		return rr.end
+	}
+	return rr.header.End
+}
+
+// Read returns the next key-value pair. The slices stay valid after
+// subsequent calls.
+func (rr *RecordReader) Read() (key, value []byte, err error) {
+	if rr.header.Version == FORMAT_V1 {
+		key, value, err = rr.readV1()
+	} else {
+		key, value, err = rr.readV2()
+	}
+	if err == nil {
+		rr.count++
+	}
+	return key, value, err
+}
+
+// Next advances to the next record, returning false at the end of the
+// file or on error (see Err).
+func (rr *RecordReader) Next() bool {
+	if rr.err != nil {
+		return false
+	}
+	rr.key, rr.value, rr.err = rr.Read()
+	return rr.err == nil
+}
+
+func (rr *RecordReader) Key() []byte {
+	return rr.key
+}
+
+func (rr *RecordReader) Value() []byte {
+	return rr.value
+}
+
+// Err returns the error that stopped Next, or nil at a clean end of file.
+func (rr *RecordReader) Err() error {
+	if rr.err == io.EOF {
+		return nil
+	}
+	return rr.err
+}
+
+// Count is the number of records read so far.
+func (rr *RecordReader) Count() int64 {
+	return rr.count
+}
+
+// Close closes the file if the reader was created by Open.
+func (rr *RecordReader) Close() (err error) {
+	for _, c := range rr.closer {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	rr.closer = nil
+	return err
+}
+
+func (rr *RecordReader) readHeader() (err error) {
+	fixed := make([]byte, len(MAGIC)+2)
+	err = rr.readFull(fixed)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read archive header")
+	}
+	rr.header.Version = int(binary.LittleEndian.Uint16(fixed[len(MAGIC):]))
+	if rr.header.Version < FORMAT_V2 || rr.header.Version > CURRENT_VERSION {
+		return errors.Errorf("Archive format version %d is not supported (up to %d)",
+			rr.header.Version, CURRENT_VERSION)
+	}
+
+	body, err := rr.readChecksummed()
+	if err != nil {
+		return errors.Wrapf(err, "Corrupted archive header")
+	}
+	return rr.header.unmarshal(body)
+}
+
+func (rr *RecordReader) readV1() (key, value []byte, err error) {
+	if rr.done {
+		return nil, nil, io.EOF
+	}
+	lbuf := make([]byte, 4)
+	_, err = io.ReadFull(rr.r, lbuf)
+	if err == io.EOF {
+		rr.done = true
+		return nil, nil, io.EOF // clean end between two records
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Unable to read record length at offset %d", rr.r.offset)
+	}
+	recordLen := binary.LittleEndian.Uint32(lbuf)
+	key = make([]byte, recordLen>>18)
+	value = make([]byte, recordLen&V1_MAX_VALUE_LEN)
+
+	err = rr.readFull(key)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Unable to read key of %d bytes at offset %d", len(key), rr.r.offset)
+	}
+	err = rr.readFull(value)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Unable to read value of %d bytes at offset %d", len(value), rr.r.offset)
+	}
+	return key, value, nil
+}
+
+func (rr *RecordReader) readV2() (key, value []byte, err error) {
+	for len(rr.block) == 0 {
+		if rr.done {
+			return nil, nil, io.EOF
+		}
+		err = rr.nextBlock()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return rr.decodeRecord()
+}
+
+// decodeRecord takes one record off the front of the current block.
+func (rr *RecordReader) decodeRecord() (key, value []byte, err error) {
+	b := rr.block
+	klen, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, nil, rr.corrupt("bad key length")
+	}
+	b = b[n:]
+	vlen, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, nil, rr.corrupt("bad value length")
+	}
+	b = b[n:]
+	if uint64(len(b)) < klen || uint64(len(b))-klen < vlen {
+		return nil, nil, rr.corrupt("record overruns block")
+	}
+	key, value = b[:klen:klen], b[klen:klen+vlen:klen+vlen]
+	rr.block = b[klen+vlen:]
+	return key, value, nil
+}
+
+// nextBlock loads and verifies the next block, or sets done at the end marker.
+func (rr *RecordReader) nextBlock() (err error) {
+	rr.blockNum++
+	block, err := rr.readChecksummed()
+	if err != nil {
+		return errors.Wrapf(err, "Bad block %d", rr.blockNum)
+	}
+	if len(block) == 0 {
+		rr.done = true
+		return rr.readTrailer()
+	}
+	rr.block = block
+	return nil
+}
+
+// readTrailer reads the optional trailer after the end marker.
+func (rr *RecordReader) readTrailer() (err error) {
+	_, err = rr.r.br.Peek(1)
+	if err == io.EOF {
+		return nil // none
+	}
+	body, err := rr.readChecksummed()
+	if err != nil {
+		return errors.Wrapf(err, "Corrupted archive trailer")
+	}
+	end, _, err := readBytes(body)
+	if err != nil {
+		return errors.Wrapf(err, "Bad end key in trailer")
+	}
+	rr.end = end
+	return nil
+}
+
+// readChecksummed reads [ length uvarint ] [ payload ] [ crc32c uint32 LE ].
+// A zero length has no payload or checksum.
+func (rr *RecordReader) readChecksummed() (payload []byte, err error) {
+	start := rr.r.offset
+	l, err := binary.ReadUvarint(rr.r)
+	if err == io.EOF {
+		return nil, errors.Wrapf(io.ErrUnexpectedEOF, "File is truncated")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	if l > MAX_BLOCK_LEN {
+		return nil, errors.Errorf("Length %d at offset %d is too large", l, start)
+	}
+	payload = make([]byte, l+4)
+	err = rr.readFull(payload)
+	if err != nil {
+		return nil, err
+	}
+	sum := binary.LittleEndian.Uint32(payload[l:])
+	payload = payload[:l]
+	if crc32.Checksum(payload, crcTable) != sum {
+		return nil, errors.Errorf("Checksum mismatch for %d bytes at offset %d", l, start)
+	}
+	return payload, nil
+}
+
+func (rr *RecordReader) corrupt(what string) error {
+	return errors.Errorf("Corrupted block %d (before offset %d): %s", rr.blockNum, rr.r.offset, what)
+}
+
+func (rr *RecordReader) readFull(b []byte) (err error) {
+	_, err = io.ReadFull(rr.r, b)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// offsetReader keeps track of the file offset, for error messages.
+type offsetReader struct {
+	br     *bufio.Reader
+	offset int64
+}
+
+func (or *offsetReader) Read(p []byte) (n int, err error) {
+	n, err = or.br.Read(p)
+	or.offset += int64(n)
+	return n, err
+}
+
+func (or *offsetReader) ReadByte() (byte, error) {
+	c, err := or.br.ReadByte()
+	if err == nil {
+		or.offset++
+	}
+	return c, err
+}
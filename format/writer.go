@@ -0,0 +1,159 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package format
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RecordWriter writes version 2 archive files.
+//
+// Nothing is written until the first record, so a range with no keys
+// leaves an empty file behind (which the archive library discards).
+// Close must be called to flush the last block and the end marker.
+type RecordWriter struct {
+	w             io.Writer
+	header        Header
+	headerWritten bool
+	closed        bool
+	block         []byte
+	end           []byte // for the trailer, nil if the file ends at header.End
+}
+
+// NewRecordWriter returns a writer to w. header.Version is always
+// CURRENT_VERSION; a zero BlockSize means DEFAULT_BLOCK_SIZE.
+func NewRecordWriter(w io.Writer, header Header) *RecordWriter {
+	header.Version = CURRENT_VERSION
+	if header.BlockSize <= 0 {
+		header.BlockSize = DEFAULT_BLOCK_SIZE
+	}
+	return &RecordWriter{
+		w:      w,
+		header: header,
+	}
+}
+
+// Write adds one key-value pair. `n` is the encoded size of the record,
+// which reaches the underlying writer once its block is full.
+func (rw *RecordWriter) Write(key, value []byte) (n int, err error) {
+	if rw.closed {
+		return 0, errors.New("Write after Close")
+	}
+	if !rw.headerWritten {
+		err = rw.writeHeader()
+		if err != nil {
+			return 0, err
+		}
+	}
+	before := len(rw.block)
+	rw.block = binary.AppendUvarint(rw.block, uint64(len(key)))
+	rw.block = binary.AppendUvarint(rw.block, uint64(len(value)))
+	rw.block = append(rw.block, key...)
+	rw.block = append(rw.block, value...)
+	n = len(rw.block) - before
+
+	if len(rw.block) >= rw.header.BlockSize {
+		err = rw.flush()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// SetEnd records that the file ends at `end` rather than at the header's
+// End, as the rest of the range goes into another file. All keys written
+// must be before `end`. It is written to the trailer by Close.
+func (rw *RecordWriter) SetEnd(end []byte) {
+	rw.end = append([]byte(nil), end...)
+}
+
+// Close flushes buffered records and writes the end marker (and trailer,
+// see SetEnd). It does not close the underlying writer.
+func (rw *RecordWriter) Close() (err error) {
+	if rw.closed || !rw.headerWritten {
+		rw.closed = true
+		return nil // no records, no file
+	}
+	err = rw.flush()
+	if err != nil {
+		return err
+	}
+	_, err = rw.w.Write(binary.AppendUvarint(nil, 0))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write end marker")
+	}
+	if rw.end != nil {
+		err = rw.writeTrailer()
+		if err != nil {
+			return err
+		}
+	}
+	rw.closed = true
+	return nil
+}
+
+func (rw *RecordWriter) writeHeader() (err error) {
+	body := rw.header.marshal()
+
+	var b []byte
+	b = append(b, MAGIC...)
+	b = binary.LittleEndian.AppendUint16(b, uint16(rw.header.Version))
+	b = binary.AppendUvarint(b, uint64(len(body)))
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(body, crcTable))
+
+	_, err = rw.w.Write(b)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write archive header")
+	}
+	rw.headerWritten = true
+	return nil
+}
+
+func (rw *RecordWriter) writeTrailer() (err error) {
+	body := binary.AppendUvarint(nil, uint64(len(rw.end)))
+	body = append(body, rw.end...)
+
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(body)))
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(body, crcTable))
+
+	_, err = rw.w.Write(b)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write archive trailer")
+	}
+	return nil
+}
+
+func (rw *RecordWriter) flush() (err error) {
+	if len(rw.block) == 0 {
+		return nil
+	}
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(rw.block)))
+	b = append(b, rw.block...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(rw.block, crcTable))
+
+	_, err = rw.w.Write(b)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write block of %d bytes", len(rw.block))
+	}
+	rw.block = rw.block[:0]
+	return nil
+}
@@ -13,6 +13,9 @@ governing permissions and limitations under the License.
 package client
 
 import (
+	"github.com/adobe/ferry/importer/session"
+	"github.com/adobe/ferry/manifest"
+	"github.com/adobe/ferry/progress"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
@@ -35,6 +38,18 @@ type ImporterClient struct {
 	dryRun        bool
 	samplingMode  bool
 	writerThreads int
+	manifestFile  string
+	batchBytes    int
+	batchKeys     int
+	onConflict    string
+
+	remapPrefixes    []string // old=new, printable form
+	remapDirectories []string // a/b=c/d
+	remap            []*ferry.PrefixRemap
+	manifest         *manifest.Manifest
+
+	// Per-file results as they come in, from all hosts
+	progress *progress.Progress
 }
 
 /*
@@ -83,6 +98,10 @@ func NewImporter(db fdb.Database,
 	for _, opt := range opts {
 		opt(exp)
 	}
+	err = session.ValidOnConflict(exp.onConflict)
+	if err != nil {
+		return nil, err
+	}
 	// if logger is not set, we must set one
 	if exp.logger == nil {
 		exp.logger, err = zap.NewProduction()
@@ -116,3 +135,38 @@ func Sample(sample bool) ImporterOption {
 		exp.samplingMode = sample
 	}
 }
+
+// ManifestFile picks a specific manifest in the source directory.
+// By default it is MANIFEST.json.
+func ManifestFile(manifestFile string) ImporterOption {
+	return func(exp *ImporterClient) {
+		exp.manifestFile = manifestFile
+	}
+}
+
+// BatchSize bounds each import transaction to `bytes` (key+value) and
+// `keys` records. Zero leaves the server default for that bound.
+func BatchSize(bytes, keys int) ImporterOption {
+	return func(exp *ImporterClient) {
+		exp.batchBytes = bytes
+		exp.batchKeys = keys
+	}
+}
+
+// OnConflict is what servers do with keys that already exist in the
+// target. See session.OnConflictPolicies.
+func OnConflict(policy string) ImporterOption {
+	return func(exp *ImporterClient) {
+		exp.onConflict = policy
+	}
+}
+
+// Remap rewrites key prefixes on import. `prefixes` are old=new pairs of
+// (printable) key prefixes, `directories` are a/b=c/d pairs of directory
+// paths; the target directory is created if needed.
+func Remap(prefixes, directories []string) ImporterOption {
+	return func(exp *ImporterClient) {
+		exp.remapPrefixes = prefixes
+		exp.remapDirectories = directories
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"io"
 	"sync"
 
+	"github.com/adobe/ferry/progress"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -31,13 +32,20 @@ func (exp *ImporterClient) ScheduleImportByNode(eg importGroup, dryRun bool) (er
 	resp, err := eg.conn.StartImportSession(context.Background(), &ferry.Target{
 		TargetUrl:     exp.targetURL,
 		ReaderThreads: int32(exp.writerThreads),
+		BatchBytes:    int64(exp.batchBytes),
+		BatchKeys:     int32(exp.batchKeys),
+		OnConflict:    exp.onConflict,
+		Remap:         exp.remap,
 	})
 	if err != nil {
 		return errors.Wrapf(err, "Unable to initiate session with peer")
 	}
 	sessionID := resp.SessionId
 
+	watched := make(chan struct{})
+	close(watched)
 	if !dryRun {
+		watched = exp.watchSession(eg, sessionID)
 		importClient, err := eg.conn.Import(context.Background())
 		if err != nil {
 			return errors.Wrapf(err, "Unable to initiate export session with peer")
@@ -75,27 +83,50 @@ func (exp *ImporterClient) ScheduleImportByNode(eg importGroup, dryRun bool) (er
 	if err != nil {
 		return errors.Wrapf(err, "Error from StopSession")
 	}
-	exp.logger.Info("Export saved", zap.Int("files", len(resp.FinalizedFiles)))
+	<-watched // the session is stopped, so the watch has seen every file
+	var importErr error
+	if resp.Status != ferry.SessionResponse_SUCCESS {
+		importErr = errors.Errorf("Import on %s failed: %s", eg.host, resp.ErrorDetails)
+	} else {
+		exp.logger.Info("Import done", zap.Int("files", len(eg.files)), zap.String("host", eg.host))
+	}
 
-	_, err = eg.conn.EndExportSession(context.Background(),
+	_, err = eg.conn.EndImportSession(context.Background(),
 		&ferry.Session{SessionId: sessionID})
 	if err != nil {
 		return errors.Wrapf(err, "Error from EndSession")
 	}
 
-	return nil
+	return importErr
 }
 
 func (exp *ImporterClient) ScheduleImport(importPlan map[string]importGroup) (err error) {
 
+	planned := 0
+	for _, eg := range importPlan {
+		planned += len(eg.files)
+	}
+	exp.progress = progress.New("Import", "files", planned)
+	if !exp.dryRun {
+		exp.progress.Estimate(exp.estimatedKeys(importPlan), 0)
+		stop := exp.progress.Report(exp.logger)
+		defer stop()
+	}
+
 	var wg sync.WaitGroup
+	var lock sync.Mutex
 	for _, plan := range importPlan {
 		wg.Add(1)
 		go func(plan importGroup, wg *sync.WaitGroup) {
 			defer wg.Done()
-			err = exp.ScheduleImportByNode(plan, exp.dryRun)
-			if err != nil {
-				exp.logger.Error("Error from worker thread", zap.Error(err))
+			errWorker := exp.ScheduleImportByNode(plan, exp.dryRun)
+			if errWorker != nil {
+				exp.logger.Error("Error from worker thread",
+					zap.String("host", plan.host),
+					zap.Error(errWorker))
+				lock.Lock()
+				err = errWorker
+				lock.Unlock()
 			}
 		}(plan, &wg)
 	}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/adobe/blackhole/lib/archive"
 	"github.com/adobe/ferry/fdbstat"
+	"github.com/adobe/ferry/manifest"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -35,12 +36,18 @@ func (exp *ImporterClient) AssignTargets() (importPlan map[string]importGroup, e
 		return nil, errors.Wrapf(err, "Failed to read TLS credentials from %s", exp.caFile)
 	}
 
-	fileList, err := archive.List(exp.targetURL)
+	fileList, err := exp.listExportedFiles()
 	if err != nil {
-		exp.logger.Warn("Unable to list files", zap.Error(err), zap.String("source", exp.targetURL))
-		return nil, errors.Wrapf(err, "Unable to list files from %s", exp.targetURL)
+		return nil, err
+	}
+	exp.remap, err = exp.resolveRemaps()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to resolve remap rules")
 	}
 	all_hosts, err := fdbstat.GetAllNodes(exp.db)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to list fdb nodes")
+	}
 
 	for _, fileName := range fileList {
 		// find the least busy (alloted) host
@@ -50,7 +57,7 @@ func (exp *ImporterClient) AssignTargets() (importPlan map[string]importGroup, e
 		least_busy_host := ""
 		current_load := -1
 		for _, host := range all_hosts {
-			if current_load < busy[host] {
+			if current_load == -1 || busy[host] < current_load {
 				least_busy_host = host
 				current_load = busy[host]
 			}
@@ -81,3 +88,42 @@ func (exp *ImporterClient) AssignTargets() (importPlan map[string]importGroup, e
 
 	return importPlan, err
 }
+
+// listExportedFiles prefers the export's manifest and only
+// falls back to listing the directory for exports without one.
+func (exp *ImporterClient) listExportedFiles() (fileList []string, err error) {
+
+	m, err := manifest.Load(exp.targetURL, exp.manifestFile)
+	if err == nil {
+		exp.manifest = m
+		exp.logger.Info("Using manifest",
+			zap.String("cluster", m.ClusterDescription),
+			zap.Time("exported-at", m.StartTime),
+			zap.Int("files", len(m.Files)))
+		for _, f := range m.Files {
+			// Only ferry's own format has the values and the range header
+			if f.ExportFormat != "" && f.ExportFormat != "archive" {
+				return nil, errors.Errorf("Unable to import %s, files in export format %s cannot be imported",
+					f.FileName, f.ExportFormat)
+			}
+			fileList = append(fileList, f.FileName)
+		}
+		return fileList, nil
+	}
+	if exp.manifestFile != "" {
+		return nil, errors.Wrapf(err, "Unable to load manifest %s", exp.manifestFile)
+	}
+	exp.logger.Warn("No usable manifest, importing every file found", zap.Error(err))
+
+	allFiles, err := archive.List(exp.targetURL)
+	if err != nil {
+		exp.logger.Warn("Unable to list files", zap.Error(err), zap.String("source", exp.targetURL))
+		return nil, errors.Wrapf(err, "Unable to list files from %s", exp.targetURL)
+	}
+	for _, f := range allFiles {
+		if !manifest.IsManifest(f) {
+			fileList = append(fileList, f)
+		}
+	}
+	return fileList, nil
+}
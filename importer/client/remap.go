@@ -0,0 +1,107 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"strings"
+
+	"github.com/adobe/ferry/finder"
+	"github.com/adobe/ferry/importer/session"
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// resolveRemaps turns the --remap-prefix and --remap-directory rules into
+// prefix rewrites for the servers.
+//
+// A directory is found in the manifest (its prefix in the source cluster)
+// and created or opened in the target. Its subdirectories, which the
+// directory layer gives unrelated prefixes, are mapped one by one.
+func (exp *ImporterClient) resolveRemaps() (remaps []*ferry.PrefixRemap, err error) {
+
+	var rules []session.PrefixRemap
+	for _, rule := range exp.remapPrefixes {
+		from, to, err := splitRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		fromKey, err := finder.ParsePrintable(from)
+		if err != nil {
+			return nil, err
+		}
+		toKey, err := finder.ParsePrintable(to)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, session.PrefixRemap{From: fromKey, To: toKey})
+	}
+
+	for _, rule := range exp.remapDirectories {
+		from, to, err := splitRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		if exp.manifest == nil || len(exp.manifest.Directories) == 0 {
+			return nil, errors.Errorf("Remapping directory %s needs a manifest listing the source directories. Use --remap-prefix instead", from)
+		}
+		from, to = strings.Trim(from, "/"), strings.Trim(to, "/")
+		found := false
+		for _, dir := range exp.manifest.Directories {
+			if dir.Path != from && !strings.HasPrefix(dir.Path, from+"/") {
+				continue
+			}
+			found = true
+			target := to + strings.TrimPrefix(dir.Path, from)
+			if exp.dryRun {
+				exp.logger.Info("DRYRUN: would remap directory",
+					zap.String("from", dir.Path),
+					zap.String("to", target))
+				continue
+			}
+			dss, err := directory.CreateOrOpen(exp.db, strings.Split(target, "/"), nil)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Unable to create or open directory %s", target)
+			}
+			exp.logger.Info("Remapping directory",
+				zap.String("from", dir.Path),
+				zap.String("from-prefix", fdb.Printable(dir.Prefix)),
+				zap.String("to", target),
+				zap.String("to-prefix", fdb.Printable(dss.Bytes())))
+			rules = append(rules, session.PrefixRemap{From: dir.Prefix, To: dss.Bytes()})
+		}
+		if !found {
+			return nil, errors.Errorf("Directory %s is not in the manifest", from)
+		}
+	}
+
+	err = session.ValidRemaps(rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		remaps = append(remaps, &ferry.PrefixRemap{From: r.From, To: r.To})
+	}
+	return remaps, nil
+}
+
+// splitRule splits "old=new"
+func splitRule(rule string) (from, to string, err error) {
+	from, to, ok := strings.Cut(rule, "=")
+	if !ok {
+		return "", "", errors.Errorf("Remap rule %s is not of the form old=new", rule)
+	}
+	return from, to, nil
+}
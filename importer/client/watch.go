@@ -0,0 +1,80 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"io"
+	"time"
+
+	ferry "github.com/adobe/ferry/rpc"
+	"go.uber.org/zap"
+)
+
+// watchSession follows the files of sessionID on eg.host as they are
+// imported. The returned channel is closed once the server ends the
+// stream, which it does when the session is stopped.
+func (exp *ImporterClient) watchSession(eg importGroup, sessionID string) (done chan struct{}) {
+
+	done = make(chan struct{})
+	watch, err := eg.conn.WatchImportSession(context.Background(), &ferry.Session{SessionId: sessionID})
+	if err != nil {
+		exp.logger.Warn("Unable to watch import session, no progress from this host",
+			zap.String("host", eg.host),
+			zap.Error(err))
+		close(done)
+		return done
+	}
+	go func() {
+		defer close(done)
+		for {
+			r, err := watch.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				exp.logger.Warn("Watch of import session ended",
+					zap.String("host", eg.host),
+					zap.Error(err))
+				return
+			}
+			exp.progress.Add(r)
+			exp.logger.Debug("File imported",
+				zap.String("host", eg.host),
+				zap.String("file", r.FileUrl),
+				zap.String("status", r.Status.String()),
+				zap.Int64("keys", r.RowCount),
+				zap.Int64("bytes", r.Bytes),
+				zap.Duration("duration", time.Duration(r.EndTime-r.StartTime)))
+		}
+	}()
+	return done
+}
+
+// estimatedKeys adds up the row counts the manifest has for the planned
+// files, 0 if there is no manifest to go by.
+func (exp *ImporterClient) estimatedKeys(importPlan map[string]importGroup) (keys int64) {
+	if exp.manifest == nil {
+		return 0
+	}
+	rows := map[string]int64{}
+	for _, f := range exp.manifest.Files {
+		rows[f.FileName] = f.RowCount
+	}
+	for _, eg := range importPlan {
+		for _, fileName := range eg.files {
+			keys += rows[fileName]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"strings"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// What to do when an imported key already exists in the target
+const ON_CONFLICT_OVERWRITE = "overwrite"         // Set it anyway (default)
+const ON_CONFLICT_SKIP_EXISTING = "skip-existing" // Keep the existing value
+const ON_CONFLICT_FAIL = "fail-on-conflict"       // Stop the import and report the key
+const ON_CONFLICT_CLEAR_FIRST = "clear-first"     // ClearRange the file's key range while loading it
+
+var OnConflictPolicies = []string{
+	ON_CONFLICT_OVERWRITE,
+	ON_CONFLICT_SKIP_EXISTING,
+	ON_CONFLICT_FAIL,
+	ON_CONFLICT_CLEAR_FIRST,
+}
+
+const MAX_CONFLICTS_REPORTED = 10
+
+// errConflict (as the cause) stops the whole session under fail-on-conflict
+var errConflict = errors.New("keys already exist in the target")
+
+// ValidOnConflict returns an error naming the valid policies
+// if `policy` is not one of them. Empty means the default.
+func ValidOnConflict(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	for _, p := range OnConflictPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return errors.Errorf("Unknown on-conflict policy %s (use one of %s)",
+		policy, strings.Join(OnConflictPolicies, ", "))
+}
+
+// applyBatch adds the writes for batch to txn, according to es.onConflict.
+// For skip-existing and fail-on-conflict every key is read first (in
+// parallel), so those policies cost a read per key.
+func (es *ImporterSession) applyBatch(txn fdb.Transaction, batch []fdb.KeyValue) (skipped int, err error) {
+
+	if es.onConflict != ON_CONFLICT_SKIP_EXISTING && es.onConflict != ON_CONFLICT_FAIL {
+		for _, kv := range batch {
+			txn.Set(kv.Key, kv.Value)
+		}
+		return 0, nil
+	}
+
+	existing := make([]fdb.FutureByteSlice, len(batch))
+	for i, kv := range batch {
+		existing[i] = txn.Get(kv.Key)
+	}
+	exists := make([]bool, len(batch))
+	for i := range batch {
+		v, err := existing[i].Get()
+		if err != nil {
+			return 0, err
+		}
+		exists[i] = v != nil
+	}
+	writes, skipped, err := es.resolveConflicts(batch, exists)
+	if err != nil {
+		return 0, err
+	}
+	for _, kv := range writes {
+		txn.Set(kv.Key, kv.Value)
+	}
+	return skipped, nil
+}
+
+// resolveConflicts picks the records of batch to write, given which of
+// their keys exist in the target (exists[i] for batch[i]).
+func (es *ImporterSession) resolveConflicts(batch []fdb.KeyValue, exists []bool) (writes []fdb.KeyValue, skipped int, err error) {
+
+	var conflicts []string
+	for i, kv := range batch {
+		if !exists[i] || es.onConflict != ON_CONFLICT_SKIP_EXISTING && es.onConflict != ON_CONFLICT_FAIL {
+			writes = append(writes, kv)
+			continue
+		}
+		skipped++
+		if len(conflicts) < MAX_CONFLICTS_REPORTED {
+			conflicts = append(conflicts, fdb.Printable(kv.Key))
+		}
+	}
+	if es.onConflict == ON_CONFLICT_FAIL && skipped > 0 {
+		return nil, 0, errors.Wrapf(errConflict, "%s: %d keys, including %s",
+			ON_CONFLICT_FAIL, skipped, strings.Join(conflicts, ", "))
+	}
+	return writes, skipped, nil
+}
+
+// clearFileRange finishes clear-first for a file: its records are in,
+// and everything before `from` was cleared along with them (see
+// importFile). What is left is [from, end), end being where the file's
+// key range ends. Logs the whole range, [begin, end).
+func (es *ImporterSession) clearFileRange(fileName string, begin, from, end []byte) (err error) {
+
+	kranges := es.remapRange(fdb.KeyRange{Begin: fdb.Key(from), End: fdb.Key(end)})
+	_, err = es.db.Transact(func(txn fdb.Transaction) (interface{}, error) {
+		for _, kr := range kranges {
+			txn.ClearRange(kr)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Unable to clear range of %s", fileName)
+	}
+	for _, kr := range es.remapRange(fdb.KeyRange{Begin: fdb.Key(begin), End: fdb.Key(end)}) {
+		es.logger.Info("Cleared on import",
+			zap.String("file", fileName),
+			zap.String("begin", fdb.Printable(kr.Begin.FDBKey())),
+			zap.String("end", fdb.Printable(kr.End.FDBKey())))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+)
+
+func TestValidOnConflict(t *testing.T) {
+	for _, policy := range append([]string{""}, OnConflictPolicies...) {
+		if err := ValidOnConflict(policy); err != nil {
+			t.Errorf("ValidOnConflict(%q): %v", policy, err)
+		}
+	}
+	for _, policy := range []string{"skip", "Overwrite", "fail"} {
+		err := ValidOnConflict(policy)
+		if err == nil || !strings.Contains(err.Error(), ON_CONFLICT_SKIP_EXISTING) {
+			t.Errorf("ValidOnConflict(%q) = %v, want an error listing the policies", policy, err)
+		}
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	batch := []fdb.KeyValue{
+		{Key: fdb.Key("a"), Value: []byte("1")},
+		{Key: fdb.Key("b"), Value: []byte("2")},
+		{Key: fdb.Key("c"), Value: []byte("3")},
+	}
+	tests := []struct {
+		policy      string
+		exists      []bool
+		wantWrites  string
+		wantSkipped int
+		wantErr     string // "" for none
+	}{
+		{ON_CONFLICT_OVERWRITE, []bool{false, true, true}, "abc", 0, ""},
+		{ON_CONFLICT_CLEAR_FIRST, []bool{true, true, true}, "abc", 0, ""},
+		{ON_CONFLICT_SKIP_EXISTING, []bool{false, false, false}, "abc", 0, ""},
+		{ON_CONFLICT_SKIP_EXISTING, []bool{false, true, true}, "a", 2, ""},
+		{ON_CONFLICT_SKIP_EXISTING, []bool{true, true, true}, "", 3, ""},
+		{ON_CONFLICT_FAIL, []bool{false, false, false}, "abc", 0, ""},
+		{ON_CONFLICT_FAIL, []bool{false, true, false}, "", 0, "1 keys, including b"},
+		{ON_CONFLICT_FAIL, []bool{true, false, true}, "", 0, "2 keys, including a, c"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %v", tt.policy, tt.exists), func(t *testing.T) {
+			es := &ImporterSession{onConflict: tt.policy}
+			writes, skipped, err := es.resolveConflicts(batch, tt.exists)
+			if tt.wantErr != "" {
+				if err == nil || errors.Cause(err) != errConflict || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Error %v, want a conflict mentioning %q", err, tt.wantErr)
+				}
+				if len(writes) != 0 {
+					t.Errorf("%d writes despite the conflict", len(writes))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			for _, kv := range writes {
+				got += string(kv.Key)
+			}
+			if got != tt.wantWrites || skipped != tt.wantSkipped {
+				t.Errorf("Wrote %q and skipped %d, want %q and %d", got, skipped, tt.wantWrites, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestConflictsReported(t *testing.T) {
+	var batch []fdb.KeyValue
+	var exists []bool
+	for i := 0; i < 3*MAX_CONFLICTS_REPORTED; i++ {
+		batch = append(batch, fdb.KeyValue{Key: fdb.Key(fmt.Sprintf("k%02d", i))})
+		exists = append(exists, true)
+	}
+	es := &ImporterSession{onConflict: ON_CONFLICT_FAIL}
+	_, _, err := es.resolveConflicts(batch, exists)
+	if err == nil {
+		t.Fatal("No conflict reported")
+	}
+	if n := len(regexp.MustCompile(`k\d\d`).FindAllString(err.Error(), -1)); n != MAX_CONFLICTS_REPORTED {
+		t.Errorf("%d keys reported, want %d: %s", n, MAX_CONFLICTS_REPORTED, err)
+	}
+}
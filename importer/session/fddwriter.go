@@ -13,145 +13,77 @@ governing permissions and limitations under the License.
 package session
 
 import (
-	"encoding/binary"
 	"fmt"
-	"io"
 	"sync"
+	"time"
 
-	"github.com/adobe/blackhole/lib/archive"
+	"github.com/adobe/ferry/format"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
-const MAX_KEY_LEN = (1 << 14) - 1   // Max 14 bits for its length. We only need 10k. Buffer till 16k
-const MAX_VALUE_LEN = (1 << 18) - 1 // Max 18 bits for its length. We only need 100k. Buffer till 260k
-
-func (es *ImporterSession) readRecord(ar archive.Archive) (key, value []byte, err error) {
-	const UINT32LEN = 4
-	var lbuf = make([]byte, UINT32LEN)
-
-	n, err := io.ReadFull(ar, lbuf)
-	if err != nil {
-		if err == io.EOF {
-			return nil, nil, err // io.EOF - Not an error
-		}
-		return nil, nil, errors.Wrapf(err, "[1] Error reading archive file")
-	}
-	if n != len(lbuf) {
-		es.logger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(lbuf)),
-		)
-		return nil, nil, errors.New("[1] Unexpected EOF for archive file")
-	}
-	recordLen := binary.LittleEndian.Uint32(lbuf)
-	keyLen := recordLen >> 18
-	valueLen := recordLen & MAX_KEY_LEN
-
-	if keyLen > 1000 {
-		return nil, nil, errors.Errorf("Long key (%d, %d, %d)",
-			recordLen, keyLen, valueLen)
-	}
-	key = make([]byte, keyLen)
-	value = make([]byte, valueLen)
-	n, err = io.ReadFull(ar, key)
-	if err != nil {
-		return nil, nil, errors.Wrapf(err, "[2] Error reading archive file")
-	}
-	if n != len(key) {
-		es.logger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(key)),
-		)
-		return nil, nil, errors.New("[2] Unexpected EOF for archive file")
-	}
-	n, err = io.ReadFull(ar, value)
-	if err != nil {
-		return nil, nil, errors.Wrapf(err, "[3] Error reading archive file")
-	}
-	if n != len(value) {
-		es.logger.Error("short read",
-			zap.Int("got", n),
-			zap.Int("expected", len(value)),
-		)
-		return nil, nil, errors.New("[3] Unexpected EOF for archive file")
-	}
-	return key, value, nil
-}
-
 func (es *ImporterSession) printStats(wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	var totalKeysRead, totalBytesRead int64
+	var totalKeysRead, totalBytesRead, totalKeysSkipped int64
 	var totalKeysLastPrinted int64
 	for stat := range es.writerStatChan {
 		totalBytesRead += stat.bytesRead
 		totalKeysRead += stat.keysRead
+		totalKeysSkipped += stat.keysSkipped
 		if totalKeysRead-totalKeysLastPrinted > 1_000_000 {
-			es.logger.Info("Progress", zap.Int64("keys", totalKeysRead), zap.Int64("bytes", totalBytesRead))
+			es.logger.Info("Progress", zap.Int64("keys", totalKeysRead), zap.Int64("bytes", totalBytesRead),
+				zap.Int64("skipped", totalKeysSkipped))
 			totalKeysLastPrinted = totalKeysRead
 		}
 	}
-	es.logger.Info("Session total", zap.Int64("keys", totalKeysRead), zap.Int64("bytes", totalBytesRead))
+	es.logger.Info("Session total", zap.Int64("keys", totalKeysRead), zap.Int64("bytes", totalBytesRead),
+		zap.Int64("skipped", totalKeysSkipped))
 }
 
+const FDB_TRANSACTION_TOO_OLD = 1007
+const FDB_TRANSACTION_TOO_LARGE = 2101
+
 func (es *ImporterSession) dbWriter(thread int) (err error) {
 
 	totalKeysWritten := int64(0)
 	es.logger.Info("Importing from", zap.String("targetURL", es.targetURL))
 
 	for fileName := range es.writerFilesChan {
-		fqfn := fmt.Sprintf("%s/%s", es.targetURL, fileName)
-		ar, err := archive.OpenArchive(fqfn, 4_000_000)
-		if err != nil {
-			return errors.Wrapf(err, "Unable to open export file %s", fqfn)
-		}
-
-		keysRead := 0
-		bytesWritten := int64(0)
-		var key, value []byte
-		for {
-			_, err = es.db.Transact(func(txn fdb.Transaction) (ret interface{}, e error) {
-				for {
-					key, value, err = es.readRecord(ar)
-					if err == io.EOF {
-						return nil, io.EOF
-					}
-					if err != nil {
-						return nil, err
-					}
-					// txn.Set(fdb.Key(key), value)
-
-					keysRead++
-					bytesWritten += int64(len(key) + len(value))
-					totalKeysWritten++
-					if bytesWritten > 4_000_000 {
-						return nil, nil
-					}
-				}
-			})
-			if err != io.EOF && err != nil {
-				es.logger.Error("transaction error",
-					zap.String("file", fqfn),
-					zap.ByteString("key", key),
-					zap.ByteString("value", value),
-					zap.Int("keysRead", keysRead),
-					zap.Int64("bytesWriten", bytesWritten),
-				)
-				return errors.Wrapf(err, "Write transaction error")
-			}
-
-			// log.Printf("NEXT: Read %d keys %d bytes", keysRead, bytesRead)
-			es.writerStatChan <- writerStat{keysRead: int64(keysRead), bytesRead: int64(bytesWritten)}
-			keysRead, bytesWritten = 0, 0 // reset to avoid double counting
-			if err == io.EOF {
-				break
-			}
+		startTime := time.Now()
+		if es.aborted() {
+			err := errors.New("Not imported, session aborted on conflict")
+			es.failures.Lock()
+			es.failures.files[fileName] = err
+			es.failures.Unlock()
+			es.addFileResult(FileResult{FileName: fileName, StartTime: startTime, EndTime: time.Now(), Err: err})
+			continue // drain the channel, Send() must not block
 		}
-		err = ar.Close()
+		keysWritten, bytesWritten, err := es.importFile(thread, fileName)
+		totalKeysWritten += keysWritten
+		es.addFileResult(FileResult{
+			FileName:  fileName,
+			StartTime: startTime,
+			EndTime:   time.Now(),
+			Keys:      keysWritten,
+			Bytes:     bytesWritten,
+			Err:       err,
+		})
 		if err != nil {
-			return errors.Wrapf(err, "Unable to close archive file")
+			// Keep going - other files may still import cleanly.
+			// The session reports this file as failed.
+			es.logger.Error("Import of file failed",
+				zap.Int("thread", thread),
+				zap.String("file", fileName),
+				zap.Int64("keys-written", keysWritten),
+				zap.Error(err))
+			es.failures.Lock()
+			es.failures.files[fileName] = err
+			if errors.Cause(err) == errConflict {
+				es.failures.abort = true
+			}
+			es.failures.Unlock()
 		}
 	}
 	es.logger.Info("Importing from",
@@ -159,5 +91,177 @@ func (es *ImporterSession) dbWriter(thread int) (err error) {
 		zap.Int64("totalKeysWritten", totalKeysWritten),
 	)
 
-	return err
+	return nil
+}
+
+// RecordSource is what ImportRecords reads from. *format.RecordReader is one.
+type RecordSource interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Err() error
+}
+
+func (es *ImporterSession) importFile(thread int, fileName string) (keysWritten, bytesWritten int64, err error) {
+
+	fqfn := fmt.Sprintf("%s/%s", es.targetURL, fileName)
+	records, err := format.Open(fqfn, 4_000_000, format.Decrypt(es.key))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer records.Close()
+
+	// clear-first clears the file's range as it loads it: everything up
+	// to each batch's last key in the batch's transaction, the rest once
+	// the whole file is read. A file that was rolled over only tells
+	// where it ends at its very end (see format.RecordReader.End), and
+	// clearing any further would drop what the next file brings.
+	var clearFrom []byte
+	if es.onConflict == ON_CONFLICT_CLEAR_FIRST {
+		header := records.Header()
+		if header.Version < format.FORMAT_V2 {
+			return 0, 0, errors.Errorf("%s needs the key range from the file header, %s is format version %d",
+				ON_CONFLICT_CLEAR_FIRST, fileName, header.Version)
+		}
+		clearFrom = append([]byte{}, header.Begin...) // not nil, even for ""
+	}
+	keysWritten, bytesWritten, clearFrom, err = es.importRecords(thread, fileName, records, clearFrom)
+	if err != nil {
+		return keysWritten, bytesWritten, errors.Wrapf(err, "Unable to import %s", fqfn)
+	}
+	if es.onConflict == ON_CONFLICT_CLEAR_FIRST {
+		err = es.clearFileRange(fileName, records.Header().Begin, clearFrom, records.End())
+		if err != nil {
+			return keysWritten, bytesWritten, err
+		}
+	}
+	return keysWritten, bytesWritten, nil
+}
+
+// ImportRecords writes everything from records, in transactions of at most
+// es.batchKeys keys and es.batchBytes bytes. Batches FDB rejects as too
+// large (or too slow) are split, and later batches are kept at the size
+// that worked. `name` is only used for logging.
+func (es *ImporterSession) ImportRecords(thread int, name string, records RecordSource) (keysWritten, bytesWritten int64, err error) {
+	keysWritten, bytesWritten, _, err = es.importRecords(thread, name, records, nil)
+	return keysWritten, bytesWritten, err
+}
+
+// importRecords is ImportRecords, also clearing the source key range from
+// clearFrom (if not nil) up to the last key read. `clearedTo` is where
+// clearing stopped.
+func (es *ImporterSession) importRecords(thread int, name string, records RecordSource, clearFrom []byte) (keysWritten, bytesWritten int64, clearedTo []byte, err error) {
+
+	clearedTo = clearFrom
+	batchKeys, batchBytes := es.batchKeys, es.batchBytes
+	var batch []fdb.KeyValue
+	var lastKey []byte
+	for {
+		batch = batch[:0]
+		bytesInBatch := 0
+		for len(batch) < batchKeys && bytesInBatch < batchBytes && records.Next() {
+			batch = append(batch, fdb.KeyValue{Key: fdb.Key(es.remapKey(records.Key())), Value: records.Value()})
+			bytesInBatch += len(records.Key()) + len(records.Value())
+			if clearFrom != nil {
+				lastKey = append(lastKey[:0], records.Key()...)
+			}
+		}
+		if err = records.Err(); err != nil {
+			return keysWritten, bytesWritten, clearedTo, errors.Wrapf(err, "Unable to read records")
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var clear []fdb.KeyRange
+		var clearTo []byte
+		if clearFrom != nil {
+			clearTo = append(append([]byte(nil), lastKey...), 0x00)
+			// clear where the keys are going, not where they came from
+			clear = es.remapRange(fdb.KeyRange{Begin: fdb.Key(clearedTo), End: fdb.Key(clearTo)})
+		}
+		largest, skipped, err := es.writeBatch(batch, clear)
+		if err != nil {
+			return keysWritten, bytesWritten, clearedTo, errors.Wrapf(err, "Write transaction error after %d keys", keysWritten)
+		}
+		if clearFrom != nil {
+			clearedTo = clearTo
+		}
+		if largest < len(batch) {
+			batchKeys = largest
+			batchBytes = bytesInBatch * largest / len(batch)
+			if batchBytes < 1 {
+				batchBytes = 1 // still at least one key per batch
+			}
+			es.logger.Info("Reduced import batch",
+				zap.Int("thread", thread),
+				zap.String("source", name),
+				zap.Int("batch-keys", batchKeys),
+				zap.Int("batch-bytes", batchBytes))
+		}
+
+		keysWritten += int64(len(batch))
+		bytesWritten += int64(bytesInBatch)
+		es.writerStatChan <- writerStat{
+			keysRead:    int64(len(batch)),
+			bytesRead:   int64(bytesInBatch),
+			keysSkipped: int64(skipped),
+		}
+
+		if len(batch) < batchKeys && bytesInBatch < batchBytes {
+			break // the file ran out before the batch filled up
+		}
+	}
+	return keysWritten, bytesWritten, clearedTo, nil
+}
+
+// writeBatch commits batch in one transaction, retrying retryable errors.
+// On transaction_too_large or transaction_too_old the batch is split in two
+// and each half written separately. `largest` is the size of the largest
+// piece that was committed in one go; `skipped` counts keys left alone
+// under skip-existing. `clear` is cleared first, in the same transaction
+// (in the first one, if split).
+func (es *ImporterSession) writeBatch(batch []fdb.KeyValue, clear []fdb.KeyRange) (largest, skipped int, err error) {
+
+	txn, err := es.db.CreateTransaction()
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "Unable to create fdb transaction")
+	}
+	for {
+		for _, kr := range clear {
+			txn.ClearRange(kr)
+		}
+		skipped, err = es.applyBatch(txn, batch)
+		if err == nil {
+			err = txn.Commit().Get()
+		}
+		if err == nil {
+			return len(batch), skipped, nil
+		}
+		errFDB, ok := err.(fdb.Error)
+		if !ok {
+			return 0, 0, err
+		}
+		if (errFDB.Code == FDB_TRANSACTION_TOO_LARGE || errFDB.Code == FDB_TRANSACTION_TOO_OLD) && len(batch) > 1 {
+			txn.Cancel()
+			half := len(batch) / 2
+			first, skippedFirst, err := es.writeBatch(batch[:half], clear)
+			if err != nil {
+				return 0, 0, err
+			}
+			second, skippedSecond, err := es.writeBatch(batch[half:], nil)
+			if err != nil {
+				return 0, 0, err
+			}
+			if second > first {
+				first = second
+			}
+			return first, skippedFirst + skippedSecond, nil
+		}
+		// Waits (with backoff) and resets txn if the error is retryable
+		err = txn.OnError(errFDB).Get()
+		if err != nil {
+			return 0, 0, err
+		}
+	}
 }
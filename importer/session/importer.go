@@ -13,8 +13,12 @@ governing permissions and limitations under the License.
 package session
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"github.com/adobe/ferry/format"
+	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -32,18 +36,79 @@ type ImporterSession struct {
 	wgStaters       *sync.WaitGroup
 	logger          *zap.Logger
 	samplingMode    bool
+	batchBytes      int
+	batchKeys       int
+	onConflict      string
+	remap           []PrefixRemap
+	key             *format.Key // decrypts .enc files, nil if none is configured
+
+	failures struct {
+		sync.Mutex
+		files map[string]error
+		abort bool // fail-on-conflict hit, import no more files
+	}
+	results struct {
+		sync.Mutex
+		files     []FileResult
+		finalized bool       // no more files will be added
+		changed   *sync.Cond // signalled when either of the above changes
+	}
+}
+
+// FileResult is the outcome of a single file sent to the session.
+type FileResult struct {
+	FileName  string
+	StartTime time.Time
+	EndTime   time.Time
+	Keys      int64 // written, including ones left alone by skip-existing
+	Bytes     int64
+	Err       error // nil on success
+}
+
+func (r FileResult) Proto() *ferry.KeyRangeResponse {
+	x := &ferry.KeyRangeResponse{
+		StartTime: r.StartTime.UnixNano(),
+		EndTime:   r.EndTime.UnixNano(),
+		Status:    ferry.KeyRangeResponse_SUCCESS,
+		FileUrl:   r.FileName,
+		RowCount:  r.Keys,
+		Bytes:     r.Bytes,
+	}
+	if r.Err != nil {
+		x.Status = ferry.KeyRangeResponse_FAILURE
+		x.ErrorDetails = r.Err.Error()
+	}
+	return x
 }
 
 type writerStat struct {
-	keysRead  int64
-	bytesRead int64
+	keysRead    int64
+	bytesRead   int64
+	keysSkipped int64 // already in the target, see ON_CONFLICT_SKIP_EXISTING
 }
 
-func NewSession(db fdb.Database, targetURL string, writerThreads int, logger *zap.Logger, samplingMode bool) (es *ImporterSession, err error) {
+const DEFAULT_BATCH_BYTES = 1_000_000 // FDB recommends transactions under 1 MB
+const MAX_BATCH_BYTES = 9_000_000     // FDB rejects transactions over 10 MB
+const DEFAULT_BATCH_KEYS = 10_000
+
+func NewSession(db fdb.Database, targetURL string, writerThreads int, logger *zap.Logger, samplingMode bool,
+	batchBytes, batchKeys int, onConflict string, remap []PrefixRemap, key *format.Key) (es *ImporterSession, err error) {
+
+	err = ValidOnConflict(onConflict)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidRemaps(remap)
+	if err != nil {
+		return nil, err
+	}
+	if onConflict == "" {
+		onConflict = ON_CONFLICT_OVERWRITE
+	}
 
 	sessionID, err := uuid.NewRandom()
 	if err != nil {
-		es.logger.Warn("Failed to create a session ID", zap.Error(err))
+		logger.Warn("Failed to create a session ID", zap.Error(err))
 		return nil, errors.Wrap(err, "Failed to create a session ID")
 	}
 	sessionIDstr := sessionID.String()
@@ -58,13 +123,30 @@ func NewSession(db fdb.Database, targetURL string, writerThreads int, logger *za
 		wgWriters:       &sync.WaitGroup{},
 		wgStaters:       &sync.WaitGroup{},
 		samplingMode:    samplingMode,
+		batchBytes:      batchBytes,
+		batchKeys:       batchKeys,
+		onConflict:      onConflict,
+		remap:           remap,
+		key:             key,
 	}
+	es.failures.files = map[string]error{}
+	es.results.changed = sync.NewCond(&es.results.Mutex)
 
 	if es.writerThreads <= 0 {
 		es.writerThreads = 1
 	}
+	if es.batchBytes <= 0 {
+		es.batchBytes = DEFAULT_BATCH_BYTES
+	}
+	if es.batchBytes > MAX_BATCH_BYTES {
+		es.batchBytes = MAX_BATCH_BYTES
+	}
+	if es.batchKeys <= 0 {
+		es.batchKeys = DEFAULT_BATCH_KEYS
+	}
 
-	es.logger.Info("Starting", zap.Int("reader threads", es.writerThreads))
+	es.logger.Info("Starting", zap.Int("reader threads", es.writerThreads),
+		zap.String("on-conflict", es.onConflict))
 	for i := 0; i < es.writerThreads; i++ {
 		es.wgWriters.Add(1)
 		go func(threadNum int, wg *sync.WaitGroup) {
@@ -91,6 +173,69 @@ func (es *ImporterSession) Send(fileName string) {
 	es.writerFilesChan <- fileName
 }
 
+func (es *ImporterSession) aborted() bool {
+	es.failures.Lock()
+	defer es.failures.Unlock()
+	return es.failures.abort
+}
+
+// Failures returns the files that could not be (fully) imported,
+// with the reason. Only complete after Finalize.
+func (es *ImporterSession) Failures() map[string]error {
+	es.failures.Lock()
+	defer es.failures.Unlock()
+	failed := map[string]error{}
+	for k, v := range es.failures.files {
+		failed[k] = v
+	}
+	return failed
+}
+
+func (es *ImporterSession) addFileResult(r FileResult) {
+	es.results.Lock()
+	defer es.results.Unlock()
+	es.results.files = append(es.results.files, r)
+	es.results.changed.Broadcast()
+}
+
+// Watch calls fn with the result of every file, in the order they finish,
+// starting from the first one. It returns once the session is finalized
+// and fn has seen all results, or early if fn fails or ctx is done.
+func (es *ImporterSession) Watch(ctx context.Context, fn func(r FileResult) error) (err error) {
+
+	stop := context.AfterFunc(ctx, func() {
+		es.results.Lock()
+		defer es.results.Unlock()
+		es.results.changed.Broadcast()
+	})
+	defer stop()
+
+	next := 0
+	for {
+		es.results.Lock()
+		for next == len(es.results.files) && !es.results.finalized && ctx.Err() == nil {
+			es.results.changed.Wait()
+		}
+		pending := es.results.files[next:len(es.results.files):len(es.results.files)]
+		finalized := es.results.finalized
+		es.results.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, r := range pending {
+			err = fn(r)
+			if err != nil {
+				return err
+			}
+		}
+		next += len(pending)
+		if finalized && len(pending) == 0 {
+			return nil
+		}
+	}
+}
+
 func (es *ImporterSession) Finalize() {
 
 	// ---------------------------------------------------
@@ -103,6 +248,11 @@ func (es *ImporterSession) Finalize() {
 		close(es.writerFilesChan)
 		es.wgWriters.Wait()
 		es.writerFilesChan = nil
+
+		es.results.Lock()
+		es.results.finalized = true
+		es.results.changed.Broadcast()
+		es.results.Unlock()
 	}
 
 	// ---------------------------------------------------
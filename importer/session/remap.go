@@ -0,0 +1,114 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"bytes"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+)
+
+// PrefixRemap rewrites imported keys starting with From to start with To
+// instead. Directory remaps are resolved to these by the client.
+type PrefixRemap struct {
+	From []byte
+	To   []byte
+}
+
+// ValidRemaps rejects rules whose From prefixes overlap, so
+// every key is rewritten by at most one rule.
+func ValidRemaps(remaps []PrefixRemap) error {
+	for i, a := range remaps {
+		for _, b := range remaps[i+1:] {
+			if bytes.HasPrefix(a.From, b.From) || bytes.HasPrefix(b.From, a.From) {
+				return errors.Errorf("Remap rules for %s and %s overlap",
+					fdb.Printable(a.From), fdb.Printable(b.From))
+			}
+		}
+	}
+	return nil
+}
+
+// remapKey returns key with the matching rule applied (if any).
+func (es *ImporterSession) remapKey(key []byte) []byte {
+	for _, r := range es.remap {
+		if bytes.HasPrefix(key, r.From) {
+			return append(append(make([]byte, 0, len(r.To)+len(key)-len(r.From)), r.To...), key[len(r.From):]...)
+		}
+	}
+	return key
+}
+
+// remapRange returns where the keys of krange end up once imported:
+// the parts covered by a rule moved under its To prefix, the rest as is.
+func (es *ImporterSession) remapRange(krange fdb.KeyRange) (kranges []fdb.KeyRange) {
+
+	unmapped := []fdb.KeyRange{krange}
+	for _, r := range es.remap {
+		from, to := prefixRange(r.From), prefixRange(r.To)
+		var rest []fdb.KeyRange
+		for _, piece := range unmapped {
+			begin, end := piece.Begin.FDBKey(), piece.End.FDBKey()
+			if bytes.Compare(begin, from.Begin.FDBKey()) < 0 {
+				rest = append(rest, fdb.KeyRange{Begin: begin, End: minKey(end, from.Begin.FDBKey())})
+				begin = from.Begin.FDBKey()
+			}
+			if bytes.Compare(end, from.End.FDBKey()) > 0 {
+				rest = append(rest, fdb.KeyRange{Begin: maxKey(begin, from.End.FDBKey()), End: end})
+				end = from.End.FDBKey()
+			}
+			if bytes.Compare(begin, end) >= 0 {
+				continue // piece was entirely outside the rule
+			}
+			mapped := fdb.KeyRange{Begin: fdb.Key(es.remapKey(begin)), End: to.End}
+			if !bytes.Equal(end, from.End.FDBKey()) {
+				mapped.End = fdb.Key(es.remapKey(end))
+			}
+			kranges = append(kranges, mapped)
+		}
+		unmapped = rest
+	}
+	for _, piece := range unmapped {
+		if bytes.Compare(piece.Begin.FDBKey(), piece.End.FDBKey()) < 0 {
+			kranges = append(kranges, piece)
+		}
+	}
+	return kranges
+}
+
+// prefixRange is fdb.PrefixRange, also for the empty prefix (all user keys).
+func prefixRange(prefix []byte) fdb.KeyRange {
+	if len(prefix) == 0 {
+		return fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("\xff")}
+	}
+	krange, err := fdb.PrefixRange(prefix)
+	if err != nil { // all \xff - nothing sorts after it
+		return fdb.KeyRange{Begin: fdb.Key(prefix), End: fdb.Key("\xff\xff")}
+	}
+	return krange
+}
+
+func minKey(a, b fdb.Key) fdb.Key {
+	if bytes.Compare(a, b) < 0 {
+		return a
+	}
+	return b
+}
+
+func maxKey(a, b fdb.Key) fdb.Key {
+	if bytes.Compare(a, b) > 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+func remaps(pairs ...string) (rules []PrefixRemap) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rules = append(rules, PrefixRemap{From: []byte(pairs[i]), To: []byte(pairs[i+1])})
+	}
+	return rules
+}
+
+func rangesString(kranges []fdb.KeyRange) string {
+	var s []string
+	for _, r := range kranges {
+		s = append(s, fmt.Sprintf("%s-%s", fdb.Printable(r.Begin.FDBKey()), fdb.Printable(r.End.FDBKey())))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestValidRemaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []PrefixRemap
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"one", remaps("a", "x"), false},
+		{"disjoint", remaps("ab", "x", "ac", "y", "b", "z"), false},
+		{"same target", remaps("a", "x", "b", "x"), false},
+		{"nested", remaps("a", "x", "ab", "y"), true},
+		{"nested, longer first", remaps("ab", "y", "a", "x"), true},
+		{"identical", remaps("a", "x", "a", "y"), true},
+		{"empty prefix", remaps("", "x", "b", "y"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidRemaps(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidRemaps = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemapKey(t *testing.T) {
+	es := &ImporterSession{remap: remaps("app/", "new/", "tmp", "")}
+	for key, want := range map[string]string{
+		"app/k1": "new/k1",
+		"app/":   "new/",
+		"app":    "app",
+		"tmpk":   "k",
+		"other":  "other",
+	} {
+		if got := string(es.remapKey([]byte(key))); got != want {
+			t.Errorf("remapKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRemapRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []PrefixRemap
+		krange fdb.KeyRange
+		want   string
+	}{
+		{"no rules", nil, fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("c")}, "a-c"},
+		{"inside the prefix", remaps("a", "x"),
+			fdb.KeyRange{Begin: fdb.Key("ab"), End: fdb.Key("ac")}, "xb-xc"},
+		{"whole prefix", remaps("a", "x"),
+			fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("b")}, "x-y"},
+		{"outside the prefix", remaps("a", "x"),
+			fdb.KeyRange{Begin: fdb.Key("m"), End: fdb.Key("n")}, "m-n"},
+		{"straddling the end", remaps("a", "x"),
+			fdb.KeyRange{Begin: fdb.Key("ab"), End: fdb.Key("c")}, "xb-y b-c"},
+		{"straddling the begin", remaps("b", "x"),
+			fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("bm")}, "x-xm a-b"},
+		{"all keys", remaps("a", "x"),
+			fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("\xff")}, "x-y -a b-\\xff"},
+		{"two rules", remaps("a", "x", "b", "y"),
+			fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("c")}, "x-y y-z -a"},
+		{"to the empty prefix", remaps("a", ""),
+			fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("b")}, "-\\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := &ImporterSession{remap: tt.rules}
+			got := rangesString(es.remapRange(tt.krange))
+			if got != tt.want {
+				t.Errorf("remapRange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
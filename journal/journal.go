@@ -0,0 +1,294 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package journal keeps track of the key ranges an export job has already
+// finished, so an interrupted job can be resumed instead of restarted.
+//
+// The journal lives in the directory JOURNAL_DIRECTORY (one subspace per
+// job) of the cluster being exported. That keeps it transactional and
+// reachable from every ferry server. Exports leave that directory out.
+package journal
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+const JOURNAL_DIRECTORY = "ferry/jobs"
+
+type Journal struct {
+	db    fdb.Database
+	jobID string
+	// lockAware lets the journal be used while the database is locked
+	// (export --lock)
+	lockAware bool
+
+	sync.Mutex                   // guards job
+	job        subspace.Subspace // nil until the journal directory is opened
+}
+
+// Scope is what an export was limited to, as given on the command line
+type Scope struct {
+	Directories []string `json:"directories,omitempty"`
+	Prefixes    []string `json:"prefixes,omitempty"`
+	Begin       string   `json:"begin,omitempty"`
+	End         string   `json:"end,omitempty"`
+}
+
+func (s Scope) Equal(o Scope) bool {
+	return slices.Equal(s.Directories, o.Directories) && slices.Equal(s.Prefixes, o.Prefixes) &&
+		s.Begin == o.Begin && s.End == o.End
+}
+
+// JobInfo is what an export was started with. A resumed
+// job must write to the same place in the same format.
+type JobInfo struct {
+	TargetURL    string    `json:"target_url"`
+	ExportFormat string    `json:"export_format"`
+	Compress     bool      `json:"compress,omitempty"` // lz4, by jobs started before Compression
+	Compression  string    `json:"compression"`        // as in format.Codec.String
+	Encrypted    bool      `json:"encrypted,omitempty"`
+	ReadPercent  int       `json:"read_percent"`
+	SampleBy     string    `json:"sample_by,omitempty"`
+	SampleSeed   string    `json:"sample_seed,omitempty"`
+	Redaction    []string  `json:"redaction,omitempty"` // the rules, as configured
+	Scope        Scope     `json:"scope"`
+	StartTime    time.Time `json:"start_time"`
+}
+
+func New(db fdb.Database, jobID string) *Journal {
+	return &Journal{
+		db:    db,
+		jobID: jobID,
+	}
+}
+
+// KeyRange is every key of every job's journal, for exports to leave out.
+// Empty if no job was ever journaled.
+func KeyRange(db fdb.Database) (kr fdb.KeyRange, err error) {
+	ds, err := directory.Open(db, strings.Split(JOURNAL_DIRECTORY, "/"), nil)
+	if err == directory.ErrDirNotExists {
+		return fdb.KeyRange{}, nil
+	}
+	if err != nil {
+		return fdb.KeyRange{}, errors.Wrapf(err, "Unable to open directory %s", JOURNAL_DIRECTORY)
+	}
+	begin, end := ds.FDBRangeKeys()
+	return fdb.KeyRange{Begin: begin.FDBKey(), End: end.FDBKey()}, nil
+}
+
+// writeSubspace is the job's subspace, creating the journal directory in
+// tr if it doesn't exist yet.
+func (j *Journal) writeSubspace(tr fdb.Transaction) (job subspace.Subspace, err error) {
+	j.Lock()
+	defer j.Unlock()
+	if j.job != nil {
+		return j.job, nil
+	}
+	ds, err := directory.CreateOrOpen(tr, strings.Split(JOURNAL_DIRECTORY, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Not cached until tr commits, it could still be rolled back
+	return ds.Sub(j.jobID), nil
+}
+
+// readSubspace is the job's subspace, nil if there is no journal directory
+func (j *Journal) readSubspace(rt fdb.ReadTransaction) (job subspace.Subspace, err error) {
+	j.Lock()
+	defer j.Unlock()
+	if j.job != nil {
+		return j.job, nil
+	}
+	ds, err := directory.Open(rt, strings.Split(JOURNAL_DIRECTORY, "/"), nil)
+	if err == directory.ErrDirNotExists {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.job = ds.Sub(j.jobID) // it exists, so it's committed
+	return j.job, nil
+}
+
+// committed caches job once the transaction that may have created its
+// directory has committed
+func (j *Journal) committed(job subspace.Subspace) {
+	j.Lock()
+	defer j.Unlock()
+	j.job = job
+}
+
+func meta(job subspace.Subspace) fdb.Key {
+	return job.Pack(tuple.Tuple{"meta"})
+}
+
+func ranges(job subspace.Subspace) subspace.Subspace {
+	return job.Sub("ranges")
+}
+
+func (j *Journal) JobID() string {
+	return j.jobID
+}
+
+// SetLockAware has every transaction of the journal go through while
+// the database is locked.
+func (j *Journal) SetLockAware() {
+	j.lockAware = true
+}
+
+func (j *Journal) writeOptions(tr fdb.Transaction) error {
+	if !j.lockAware {
+		return nil
+	}
+	return tr.Options().SetLockAware()
+}
+
+func (j *Journal) readOptions(rt fdb.ReadTransaction) error {
+	if !j.lockAware {
+		return nil
+	}
+	return rt.Options().SetReadLockAware()
+}
+
+// write runs f with the job's subspace in a transaction
+func (j *Journal) write(f func(tr fdb.Transaction, job subspace.Subspace)) (err error) {
+	ret, err := j.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		err := j.writeOptions(tr)
+		if err != nil {
+			return nil, err
+		}
+		job, err := j.writeSubspace(tr)
+		if err != nil {
+			return nil, err
+		}
+		f(tr, job)
+		return job, nil
+	})
+	if err != nil {
+		return err
+	}
+	j.committed(ret.(subspace.Subspace))
+	return nil
+}
+
+func (j *Journal) Start(info JobInfo) (err error) {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to serialize job info")
+	}
+	err = j.write(func(tr fdb.Transaction, job subspace.Subspace) {
+		tr.Set(meta(job), b)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Unable to record job %s", j.jobID)
+	}
+	return nil
+}
+
+func (j *Journal) Info() (info *JobInfo, err error) {
+	ret, err := j.db.ReadTransact(func(rt fdb.ReadTransaction) (interface{}, error) {
+		err := j.readOptions(rt)
+		if err != nil {
+			return nil, err
+		}
+		job, err := j.readSubspace(rt)
+		if err != nil || job == nil {
+			return nil, err
+		}
+		return rt.Get(meta(job)).Get()
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read job %s", j.jobID)
+	}
+	b, _ := ret.([]byte)
+	if b == nil {
+		return nil, errors.Errorf("No such job: %s", j.jobID)
+	}
+	info = &JobInfo{}
+	err = json.Unmarshal(b, info)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Corrupted job info for %s", j.jobID)
+	}
+	if info.Compression == "" {
+		info.Compression = "none"
+		if info.Compress {
+			info.Compression = "lz4"
+		}
+	}
+	return info, nil
+}
+
+// Record marks the range covered by `ff` as done.
+func (j *Journal) Record(ff *ferry.FinalizedFile) (err error) {
+	b, err := proto.Marshal(ff)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to serialize finalized file")
+	}
+	err = j.write(func(tr fdb.Transaction, job subspace.Subspace) {
+		tr.Set(ranges(job).Pack(tuple.Tuple{ff.BeginKey, ff.EndKey}), b)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Unable to journal range %s", ff.KeyRange)
+	}
+	return nil
+}
+
+// Completed returns every file recorded so far, in key order.
+func (j *Journal) Completed() (files []*ferry.FinalizedFile, err error) {
+	ret, err := j.db.ReadTransact(func(rt fdb.ReadTransaction) (interface{}, error) {
+		err := j.readOptions(rt)
+		if err != nil {
+			return nil, err
+		}
+		job, err := j.readSubspace(rt)
+		if err != nil || job == nil {
+			return nil, err
+		}
+		return rt.GetRange(ranges(job), fdb.RangeOptions{Mode: fdb.StreamingModeWantAll}).GetSliceWithError()
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read journal for job %s", j.jobID)
+	}
+	kvs, _ := ret.([]fdb.KeyValue)
+	for _, kv := range kvs {
+		ff := &ferry.FinalizedFile{}
+		err = proto.Unmarshal(kv.Value, ff)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Corrupted journal entry %s", fdb.Printable(kv.Key))
+		}
+		files = append(files, ff)
+	}
+	return files, nil
+}
+
+// Clear drops the job. Called once an export finished without gaps.
+func (j *Journal) Clear() (err error) {
+	err = j.write(func(tr fdb.Transaction, job subspace.Subspace) {
+		tr.ClearRange(job)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Unable to clear journal for job %s", j.jobID)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package manifest describes a complete export - every file, the key range
+// it covers and how it was written - so an export directory can be consumed
+// without any out-of-band information.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adobe/blackhole/lib/archive"
+	ferry "github.com/adobe/ferry/rpc"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const MANIFEST_VERSION = 1 // Bump on any incompatible change to Manifest below
+const MANIFEST_FILE = "MANIFEST.json"
+
+type Manifest struct {
+	Version            int       `json:"version"`
+	JobID              string    `json:"job_id"`
+	ClusterDescription string    `json:"cluster_description"`
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	Files              []File    `json:"files"`
+
+	// Directory layer of the source cluster at export time. Lets an
+	// import map a directory to wherever it lives in the target cluster.
+	Directories []Directory `json:"directories,omitempty"`
+
+	// Set if only some keys were exported (--read-percent)
+	Sample *Sample `json:"sample,omitempty"`
+	// Rules values were masked with before export, if any
+	Redaction []RedactRule `json:"redaction,omitempty"`
+	// Commit version of the database lock held during the export
+	// (--lock), 0 if it wasn't locked. Every file is as of this version.
+	LockVersion int64 `json:"lock_version,omitempty"`
+
+	sync.Mutex `json:"-"` // AddFiles is called from one goroutine per host
+}
+
+// File is one exported file. Begin and End are raw keys (base64 in JSON);
+// KeyRange is the same range in fdb.Printable form for humans.
+type File struct {
+	FileName      string    `json:"file_name"`
+	Begin         []byte    `json:"begin"`
+	End           []byte    `json:"end"`
+	KeyRange      string    `json:"key_range"`
+	RowCount      int64     `json:"row_count"`
+	ContentSize   int64     `json:"content_size"`
+	Checksum      string    `json:"checksum"`
+	Compression   string    `json:"compression"`
+	FormatVersion int       `json:"format_version"`
+	ExportFormat  string    `json:"export_format"`
+	KeyID         string    `json:"key_id,omitempty"` // encryption key, not the key itself
+	Host          string    `json:"host"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+}
+
+// Sample is how a sampled export picked its keys. Hashed samples
+// (By "key" or "tuple:N") with the same seed keep the same keys.
+type Sample struct {
+	ReadPercent int    `json:"read_percent"`
+	By          string `json:"by,omitempty"` // "" is random
+	Seed        string `json:"seed,omitempty"`
+}
+
+// RedactRule is one rule values were masked with, for one directory
+// (or raw prefix). The salt used to hash or tokenize is never recorded.
+type RedactRule struct {
+	Directory       string `json:"directory,omitempty"` // "" for prefix rules
+	Prefix          []byte `json:"prefix"`
+	PrefixPrintable string `json:"prefix_printable"`
+	Action          string `json:"action"`            // drop|null|hash|tokenize|replace
+	Element         int    `json:"element,omitempty"` // 1-based tuple element of the value, 0 for all of it
+	Value           string `json:"value,omitempty"`   // for replace
+}
+
+// Directory is one directory-layer directory and the prefix it had.
+type Directory struct {
+	Path            string `json:"path"` // a/b/c
+	Prefix          []byte `json:"prefix"`
+	PrefixPrintable string `json:"prefix_printable"`
+}
+
+func New(jobID, clusterDescription string) *Manifest {
+	return &Manifest{
+		Version:            MANIFEST_VERSION,
+		JobID:              jobID,
+		ClusterDescription: clusterDescription,
+		StartTime:          time.Now(),
+	}
+}
+
+// AddFiles records files written by `host`, or by the host a file
+// records itself, as files carried over from the journal of an earlier,
+// interrupted run of the same job do.
+func (m *Manifest) AddFiles(host string, finalizedFiles []*ferry.FinalizedFile) {
+	m.Lock()
+	defer m.Unlock()
+	for _, ff := range finalizedFiles {
+		if ff.ShellOnly {
+			continue // nothing on disk to describe
+		}
+		m.Files = append(m.Files, File{
+			FileName:      ff.FileName,
+			Begin:         ff.BeginKey,
+			End:           ff.EndKey,
+			KeyRange:      ff.KeyRange,
+			RowCount:      ff.RowCount,
+			ContentSize:   ff.ContentSize,
+			Checksum:      ff.Checksum,
+			Compression:   ff.Compression,
+			FormatVersion: int(ff.FormatVersion),
+			ExportFormat:  ff.ExportFormat,
+			KeyID:         ff.KeyId,
+			Host:          fileHost(ff, host),
+			StartTime:     time.Unix(0, ff.StartTime),
+			EndTime:       time.Unix(0, ff.EndTime),
+		})
+	}
+}
+
+func fileHost(ff *ferry.FinalizedFile, host string) string {
+	if ff.Host != "" {
+		return ff.Host
+	}
+	return host
+}
+
+func (f File) Range() fdb.KeyRange {
+	return fdb.KeyRange{Begin: fdb.Key(f.Begin), End: fdb.Key(f.End)}
+}
+
+// Save writes the manifest to targetURL as MANIFEST_FILE, replacing the
+// one from an earlier run of the job, and returns the file name.
+func (m *Manifest) Save(targetURL string, logger *zap.Logger) (fileName string, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.EndTime = time.Now()
+	sort.Slice(m.Files, func(i, j int) bool {
+		return string(m.Files[i].Begin) < string(m.Files[j].Begin)
+	})
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to serialize manifest")
+	}
+
+	err = putFile(targetURL, MANIFEST_FILE, b)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to write manifest file")
+	}
+	logger.Debug("Manifest written", zap.String("url", targetURL), zap.Int("bytes", len(b)))
+	return MANIFEST_FILE, nil
+}
+
+// Load reads the manifest `fileName` from sourceURL, MANIFEST_FILE
+// if fileName is empty.
+func Load(sourceURL, fileName string) (m *Manifest, err error) {
+
+	if fileName == "" {
+		fileName = MANIFEST_FILE
+	}
+	fqfn := fmt.Sprintf("%s/%s", strings.TrimSuffix(sourceURL, "/"), fileName)
+	ar, err := archive.OpenArchive(fqfn, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to open manifest %s", fqfn)
+	}
+	defer ar.Close()
+
+	b, err := io.ReadAll(ar)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read manifest %s", fqfn)
+	}
+	m = &Manifest{}
+	err = json.Unmarshal(b, m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to parse manifest %s", fqfn)
+	}
+	if m.Version > MANIFEST_VERSION {
+		return nil, errors.Errorf("Manifest %s is version %d, only up to %d is supported",
+			fqfn, m.Version, MANIFEST_VERSION)
+	}
+	return m, nil
+}
+
+func IsManifest(fileName string) bool {
+	return fileName == MANIFEST_FILE
+}
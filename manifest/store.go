@@ -0,0 +1,184 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+)
+
+// The archive library always names files <prefix>_<timestamp>_<random>,
+// so a file with a fixed name is written here instead, with the same URL
+// forms (and the same credentials) as the archive library. Files are
+// removed here too, as the archive library can't delete from s3.
+
+var storeURLRegex = regexp.MustCompile("^([^/:]+)://([^/]+)/?(.*?)$")
+
+// putFile writes b as `name` in directory targetURL. Readers never see a
+// partial file: a local file is written under a temporary name and
+// renamed, s3 and azure blob uploads only become visible once complete.
+func putFile(targetURL, name string, b []byte) (err error) {
+
+	if !strings.Contains(targetURL, "://") || strings.HasPrefix(targetURL, "file://") {
+		return putLocalFile(strings.TrimPrefix(targetURL, "file://"), name, b)
+	}
+	parts := storeURLRegex.FindStringSubmatch(targetURL)
+	if len(parts) != 4 {
+		return errors.Errorf("Unable to parse store url %s", targetURL)
+	}
+	bucket, key := parts[2], path.Join(parts[3], name)
+	switch strings.ToLower(parts[1]) {
+	case "s3":
+		client, err := s3Client()
+		if err != nil {
+			return err
+		}
+		_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &key,
+			Body:   bytes.NewReader(b),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Unable to upload %s", targetURL)
+		}
+		return nil
+	case "az":
+		container, err := azContainer(bucket)
+		if err != nil {
+			return err
+		}
+		_, err = azblob.UploadBufferToBlockBlob(context.Background(), b, container.NewBlockBlobURL(key),
+			azblob.UploadToBlockBlobOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "Unable to upload %s", targetURL)
+		}
+		return nil
+	}
+	return errors.Errorf("Unsupported store url %s", targetURL)
+}
+
+// RemoveFile deletes `name` from directory targetURL. A file that is
+// already gone is not an error.
+func RemoveFile(targetURL, name string) (err error) {
+
+	if !strings.Contains(targetURL, "://") || strings.HasPrefix(targetURL, "file://") {
+		err = os.Remove(filepath.Join(strings.TrimPrefix(targetURL, "file://"), name))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Unable to remove %s", name)
+		}
+		return nil
+	}
+	parts := storeURLRegex.FindStringSubmatch(targetURL)
+	if len(parts) != 4 {
+		return errors.Errorf("Unable to parse store url %s", targetURL)
+	}
+	bucket, key := parts[2], path.Join(parts[3], name)
+	switch strings.ToLower(parts[1]) {
+	case "s3":
+		client, err := s3Client()
+		if err != nil {
+			return err
+		}
+		_, err = client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &key,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Unable to delete %s from %s", name, targetURL)
+		}
+		return nil
+	case "az":
+		container, err := azContainer(bucket)
+		if err != nil {
+			return err
+		}
+		_, err = container.NewBlobURL(key).Delete(context.Background(),
+			azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+		if serr, ok := err.(azblob.StorageError); ok && serr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Unable to delete %s from %s", name, targetURL)
+		}
+		return nil
+	}
+	return errors.Errorf("Unsupported store url %s", targetURL)
+}
+
+func s3Client() (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load default s3 config")
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+func azContainer(bucket string) (container azblob.ContainerURL, err error) {
+	accountName, accountKey := os.Getenv("AZURE_STORAGE_ACCOUNT"), os.Getenv("AZURE_STORAGE_ACCESS_KEY")
+	if accountName == "" || accountKey == "" {
+		return container, errors.New("Either the AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_ACCESS_KEY environment variable is not set")
+	}
+	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+	if err != nil {
+		return container, errors.Wrapf(err, "Invalid azure credentials")
+	}
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", accountName, bucket))
+	if err != nil {
+		return container, errors.Wrapf(err, "Unable to parse azure container %s", bucket)
+	}
+	return azblob.NewContainerURL(*u, azblob.NewPipeline(credential, azblob.PipelineOptions{})), nil
+}
+
+func putLocalFile(dir, name string, b []byte) (err error) {
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s", dir)
+	}
+	fp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s in %s", name, dir)
+	}
+	defer os.Remove(fp.Name()) // a no-op once renamed
+	_, err = fp.Write(b)
+	if err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write %s", fp.Name())
+	}
+	err = os.Chmod(fp.Name(), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to chmod %s", fp.Name())
+	}
+	err = os.Rename(fp.Name(), filepath.Join(dir, name))
+	if err != nil {
+		return errors.Wrapf(err, "Unable to rename %s to %s", fp.Name(), name)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+// Package progress adds up the per-range (or per-file) results streamed
+// back by every node of a job, and shows them to whoever runs the client:
+// as a progress bar on a terminal, or as periodic log lines otherwise.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ferry "github.com/adobe/ferry/rpc"
+	"go.uber.org/zap"
+)
+
+const LOG_INTERVAL = 10 * time.Second // without a terminal
+const BAR_INTERVAL = time.Second
+const BAR_WIDTH = 30
+
+type Progress struct {
+	sync.Mutex
+	job       string // "Export", "Import" ...
+	unit      string // what `total` counts, "ranges", "files" ...
+	startTime time.Time
+
+	total     int
+	done      int
+	failures  int // failed attempts, one unit can fail more than once
+	transient int // ... of which were TRANSIENT
+	keys      int64
+	bytes     int64
+	retries   int64
+
+	// Expected totals, if known. The ETA is based on bytes if
+	// estimatedBytes is set, else on keys, else on units done.
+	estimatedKeys  int64
+	estimatedBytes int64
+}
+
+func New(job, unit string, total int) *Progress {
+	return &Progress{
+		job:       job,
+		unit:      unit,
+		total:     total,
+		startTime: time.Now(),
+	}
+}
+
+// Estimate sets the expected totals. Either can be 0 (unknown).
+func (p *Progress) Estimate(keys, bytes int64) {
+	p.Lock()
+	defer p.Unlock()
+	p.estimatedKeys = keys
+	p.estimatedBytes = bytes
+}
+
+// Add counts one result as sent by WatchExportSession/WatchImportSession
+func (p *Progress) Add(r *ferry.KeyRangeResponse) {
+	p.Lock()
+	defer p.Unlock()
+	p.retries += int64(r.Retries)
+	switch r.Status {
+	case ferry.KeyRangeResponse_SUCCESS:
+		p.done++
+		p.keys += r.RowCount
+		p.bytes += r.Bytes
+	case ferry.KeyRangeResponse_TRANSIENT:
+		p.transient++
+		p.failures++
+	default:
+		p.failures++
+	}
+}
+
+// fraction done, from the best estimate available. It stays
+// under 1 until every unit is done, estimates being estimates.
+func (p *Progress) fraction() float64 {
+	var f float64
+	switch {
+	case p.estimatedBytes > 0:
+		f = float64(p.bytes) / float64(p.estimatedBytes)
+	case p.estimatedKeys > 0:
+		f = float64(p.keys) / float64(p.estimatedKeys)
+	case p.total > 0:
+		f = float64(p.done) / float64(p.total)
+	}
+	if p.done >= p.total {
+		return 1
+	}
+	if f > 0.99 {
+		f = 0.99
+	}
+	return f
+}
+
+// eta is 0 until there is something to extrapolate from
+func (p *Progress) eta() time.Duration {
+	f := p.fraction()
+	if f <= 0 || f >= 1 {
+		return 0
+	}
+	elapsed := time.Since(p.startTime)
+	return time.Duration(float64(elapsed) * (1 - f) / f).Round(time.Second)
+}
+
+func (p *Progress) log(logger *zap.Logger, msg string) {
+	p.Lock()
+	defer p.Unlock()
+	seconds := time.Since(p.startTime).Seconds()
+	logger.Info(msg,
+		zap.String("job", p.job),
+		zap.Int(p.unit+"-done", p.done),
+		zap.Int(p.unit, p.total),
+		zap.Int("failures", p.failures),
+		zap.Int("transient-failures", p.transient),
+		zap.Int64("keys", p.keys),
+		zap.Int64("bytes", p.bytes),
+		zap.Int64("retries", p.retries),
+		zap.Int64("keys/s", int64(float64(p.keys)/seconds)),
+		zap.Int64("bps", int64(float64(p.bytes)/seconds)),
+		zap.Int("percent", int(p.fraction()*100)),
+		zap.Duration("eta", p.eta()))
+}
+
+// bar renders one line, without a line ending
+func (p *Progress) bar() string {
+	p.Lock()
+	defer p.Unlock()
+	f := p.fraction()
+	filled := int(f * BAR_WIDTH)
+	seconds := time.Since(p.startTime).Seconds()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s [%s%s] %3d%%  %d/%d %s  %d keys  %s  %s/s",
+		p.job,
+		strings.Repeat("=", filled), strings.Repeat(" ", BAR_WIDTH-filled),
+		int(f*100),
+		p.done, p.total, p.unit,
+		p.keys,
+		humanBytes(float64(p.bytes)),
+		humanBytes(float64(p.bytes)/seconds))
+	if p.failures > 0 {
+		fmt.Fprintf(&sb, "  %d failed", p.failures)
+	}
+	if eta := p.eta(); eta > 0 {
+		fmt.Fprintf(&sb, "  ETA %s", eta)
+	}
+	return sb.String()
+}
+
+// Report shows progress until the returned stop is called: redrawn in
+// place every BAR_INTERVAL if stdout is a terminal, otherwise logged
+// every LOG_INTERVAL. stop shows the totals one last time.
+func (p *Progress) Report(logger *zap.Logger) (stop func()) {
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	tty := isTerminal(os.Stdout)
+	interval := LOG_INTERVAL
+	if tty {
+		interval = BAR_INTERVAL
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				if tty {
+					p.draw(os.Stdout, false)
+				} else {
+					p.log(logger, p.job+" progress")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+		if tty {
+			p.draw(os.Stdout, true)
+		}
+		p.log(logger, p.job+" totals")
+	}
+}
+
+func (p *Progress) draw(w io.Writer, final bool) {
+	// \r goes back to the start of the line, \x1b[K clears what is left of
+	// the previous (possibly longer) line. Log lines go to stderr and may
+	// still interleave - the bar is redrawn below them on the next tick.
+	fmt.Fprintf(w, "\r%s\x1b[K", p.bar())
+	if final {
+		fmt.Fprintln(w)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func humanBytes(b float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for b >= 1000 && i < len(units)-1 {
+		b /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
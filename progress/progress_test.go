@@ -0,0 +1,125 @@
+/*
+Copyright 2021 Adobe. All rights reserved.
+This file is licensed to you under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License. You may obtain a copy
+of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed under
+the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR REPRESENTATIONS
+OF ANY KIND, either express or implied. See the License for the specific language
+governing permissions and limitations under the License.
+*/
+
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ferry "github.com/adobe/ferry/rpc"
+)
+
+func TestAdd(t *testing.T) {
+	p := New("Export", "ranges", 4)
+	p.Add(&ferry.KeyRangeResponse{Status: ferry.KeyRangeResponse_SUCCESS, RowCount: 10, Bytes: 100, Retries: 1})
+	p.Add(&ferry.KeyRangeResponse{Status: ferry.KeyRangeResponse_TRANSIENT, RowCount: 5, Bytes: 50})
+	p.Add(&ferry.KeyRangeResponse{Status: ferry.KeyRangeResponse_FAILURE, Retries: 2})
+	p.Add(&ferry.KeyRangeResponse{Status: ferry.KeyRangeResponse_SUCCESS, RowCount: 1, Bytes: 7})
+	if p.done != 2 || p.failures != 2 || p.transient != 1 || p.keys != 11 || p.bytes != 107 || p.retries != 3 {
+		t.Errorf("Added up to %+v", p)
+	}
+}
+
+func TestFraction(t *testing.T) {
+	tests := []struct {
+		name           string
+		total, done    int
+		keys, bytes    int64
+		estKeys, estBy int64
+		want           float64
+	}{
+		{"nothing to do", 0, 0, 0, 0, 0, 0, 1},
+		{"nothing done", 4, 0, 0, 0, 0, 0, 0},
+		{"by units", 4, 1, 0, 0, 0, 0, 0.25},
+		{"all units done", 4, 4, 0, 0, 0, 0, 1},
+		{"by keys", 4, 1, 30, 0, 100, 0, 0.3},
+		{"by bytes over keys", 4, 1, 30, 500, 100, 1000, 0.5},
+		{"estimate exceeded", 4, 3, 0, 2000, 0, 1000, 0.99},
+		{"done below the estimate", 4, 4, 0, 10, 0, 1000, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New("Export", "ranges", tt.total)
+			p.done, p.keys, p.bytes = tt.done, tt.keys, tt.bytes
+			p.Estimate(tt.estKeys, tt.estBy)
+			if got := p.fraction(); got != tt.want {
+				t.Errorf("fraction = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEta(t *testing.T) {
+	p := New("Export", "ranges", 4)
+	p.startTime = time.Now().Add(-30 * time.Second)
+	if eta := p.eta(); eta != 0 {
+		t.Errorf("ETA %s with nothing done, want none", eta)
+	}
+	p.done = 1
+	if eta := p.eta(); eta < 89*time.Second || eta > 91*time.Second {
+		t.Errorf("ETA %s a quarter of the way in after 30s, want 90s", eta)
+	}
+	p.done = 4
+	if eta := p.eta(); eta != 0 {
+		t.Errorf("ETA %s when done, want none", eta)
+	}
+}
+
+func TestBar(t *testing.T) {
+	p := New("Import", "files", 4)
+	p.startTime = time.Now().Add(-10 * time.Second)
+	p.done, p.keys, p.bytes = 2, 1234, 2_500_000
+
+	bar := p.bar()
+	for _, want := range []string{
+		"Import [" + strings.Repeat("=", BAR_WIDTH/2) + strings.Repeat(" ", BAR_WIDTH-BAR_WIDTH/2) + "]",
+		" 50%", "2/4 files", "1234 keys", "2.5 MB", "ETA 10s",
+	} {
+		if !strings.Contains(bar, want) {
+			t.Errorf("%q doesn't show %q", bar, want)
+		}
+	}
+	if strings.Contains(bar, "failed") {
+		t.Errorf("%q shows failures without any", bar)
+	}
+
+	p.done, p.failures = 4, 1
+	bar = p.bar()
+	if !strings.Contains(bar, "["+strings.Repeat("=", BAR_WIDTH)+"] 100%") ||
+		!strings.Contains(bar, "1 failed") || strings.Contains(bar, "ETA") {
+		t.Errorf("Finished bar is %q", bar)
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	for _, tt := range []struct {
+		b    float64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 kB"},
+		{1550, "1.6 kB"},
+		{999_000, "999.0 kB"},
+		{1e6, "1.0 MB"},
+		{2.5e9, "2.5 GB"},
+		{1e12, "1.0 TB"},
+		{1e15, "1.0 PB"},
+		{5e18, "5000.0 PB"},
+	} {
+		if got := humanBytes(tt.b); got != tt.want {
+			t.Errorf("humanBytes(%v) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
@@ -66,7 +66,7 @@ func (x KeyRangeResponse_OpStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use KeyRangeResponse_OpStatus.Descriptor instead.
 func (KeyRangeResponse_OpStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{6, 0}
+	return file_ferry_proto_rawDescGZIP(), []int{8, 0}
 }
 
 type SessionResponse_OpStatus int32
@@ -112,7 +112,7 @@ func (x SessionResponse_OpStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use SessionResponse_OpStatus.Descriptor instead.
 func (SessionResponse_OpStatus) EnumDescriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{8, 0}
+	return file_ferry_proto_rawDescGZIP(), []int{10, 0}
 }
 
 type SessionResponse_SessionState int32
@@ -161,7 +161,7 @@ func (x SessionResponse_SessionState) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use SessionResponse_SessionState.Descriptor instead.
 func (SessionResponse_SessionState) EnumDescriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{8, 1}
+	return file_ferry_proto_rawDescGZIP(), []int{10, 1}
 }
 
 type ImportRequest struct {
@@ -397,11 +397,29 @@ type Target struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	TargetUrl     string `protobuf:"bytes,1,opt,name=target_url,json=targetUrl,proto3" json:"target_url,omitempty"`
-	ReaderThreads int32  `protobuf:"varint,2,opt,name=reader_threads,json=readerThreads,proto3" json:"reader_threads,omitempty"`
-	Compress      bool   `protobuf:"varint,3,opt,name=compress,proto3" json:"compress,omitempty"`
-	ReadPercent   int32  `protobuf:"varint,4,opt,name=read_percent,json=readPercent,proto3" json:"read_percent,omitempty"`
-	ExportFormat  string `protobuf:"bytes,5,opt,name=export_format,json=exportFormat,proto3" json:"export_format,omitempty"`
+	TargetUrl      string         `protobuf:"bytes,1,opt,name=target_url,json=targetUrl,proto3" json:"target_url,omitempty"`
+	ReaderThreads  int32          `protobuf:"varint,2,opt,name=reader_threads,json=readerThreads,proto3" json:"reader_threads,omitempty"`
+	Compress       bool           `protobuf:"varint,3,opt,name=compress,proto3" json:"compress,omitempty"` // export: lz4, kept for older clients. See compression
+	ReadPercent    int32          `protobuf:"varint,4,opt,name=read_percent,json=readPercent,proto3" json:"read_percent,omitempty"`
+	ExportFormat   string         `protobuf:"bytes,5,opt,name=export_format,json=exportFormat,proto3" json:"export_format,omitempty"`
+	JobId          string         `protobuf:"bytes,6,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`                                  // export job, used to journal finished ranges
+	BatchBytes     int64          `protobuf:"varint,7,opt,name=batch_bytes,json=batchBytes,proto3" json:"batch_bytes,omitempty"`                  // import: max bytes written per transaction
+	BatchKeys      int32          `protobuf:"varint,8,opt,name=batch_keys,json=batchKeys,proto3" json:"batch_keys,omitempty"`                     // import: max keys written per transaction
+	OnConflict     string         `protobuf:"bytes,9,opt,name=on_conflict,json=onConflict,proto3" json:"on_conflict,omitempty"`                   // import: overwrite|skip-existing|fail-on-conflict|clear-first
+	Remap          []*PrefixRemap `protobuf:"bytes,10,rep,name=remap,proto3" json:"remap,omitempty"`                                              // import: rewrite key prefixes
+	MaxBytesPerSec int64          `protobuf:"varint,11,opt,name=max_bytes_per_sec,json=maxBytesPerSec,proto3" json:"max_bytes_per_sec,omitempty"` // export: this node's read limit, 0 = none
+	MaxKeysPerSec  int64          `protobuf:"varint,12,opt,name=max_keys_per_sec,json=maxKeysPerSec,proto3" json:"max_keys_per_sec,omitempty"`
+	BatchPriority  bool           `protobuf:"varint,13,opt,name=batch_priority,json=batchPriority,proto3" json:"batch_priority,omitempty"`   // export: read at batch priority
+	TransactionTag string         `protobuf:"bytes,14,opt,name=transaction_tag,json=transactionTag,proto3" json:"transaction_tag,omitempty"` // export: tag read transactions, for ratekeeper
+	Backoff        bool           `protobuf:"varint,15,opt,name=backoff,proto3" json:"backoff,omitempty"`                                    // export: slow down while the cluster is struggling
+	Compression    string         `protobuf:"bytes,16,opt,name=compression,proto3" json:"compression,omitempty"`                             // export: none|lz4|zstd[:level]|gzip[:level], overrides compress
+	Encrypt        bool           `protobuf:"varint,17,opt,name=encrypt,proto3" json:"encrypt,omitempty"`                                    // export: encrypt files with the key configured on each node
+	MaxFileSize    int64          `protobuf:"varint,18,opt,name=max_file_size,json=maxFileSize,proto3" json:"max_file_size,omitempty"`       // export: roll over to a new file past this many bytes, 0 = one file per range
+	SampleBy       string         `protobuf:"bytes,19,opt,name=sample_by,json=sampleBy,proto3" json:"sample_by,omitempty"`                   // export: how read_percent picks keys, random|key|tuple:N
+	SampleSeed     string         `protobuf:"bytes,20,opt,name=sample_seed,json=sampleSeed,proto3" json:"sample_seed,omitempty"`             // export: seed for hashed sampling
+	Redact         []*RedactRule  `protobuf:"bytes,21,rep,name=redact,proto3" json:"redact,omitempty"`                                       // export: mask values before writing them
+	LockAware      bool           `protobuf:"varint,22,opt,name=lock_aware,json=lockAware,proto3" json:"lock_aware,omitempty"`               // export: read while the client holds the database lock
+	Host           string         `protobuf:"bytes,23,opt,name=host,proto3" json:"host,omitempty"`                                           // export: this node, as the client addresses it, journaled with each file
 }
 
 func (x *Target) Reset() {
@@ -471,6 +489,258 @@ func (x *Target) GetExportFormat() string {
 	return ""
 }
 
+func (x *Target) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *Target) GetBatchBytes() int64 {
+	if x != nil {
+		return x.BatchBytes
+	}
+	return 0
+}
+
+func (x *Target) GetBatchKeys() int32 {
+	if x != nil {
+		return x.BatchKeys
+	}
+	return 0
+}
+
+func (x *Target) GetOnConflict() string {
+	if x != nil {
+		return x.OnConflict
+	}
+	return ""
+}
+
+func (x *Target) GetRemap() []*PrefixRemap {
+	if x != nil {
+		return x.Remap
+	}
+	return nil
+}
+
+func (x *Target) GetMaxBytesPerSec() int64 {
+	if x != nil {
+		return x.MaxBytesPerSec
+	}
+	return 0
+}
+
+func (x *Target) GetMaxKeysPerSec() int64 {
+	if x != nil {
+		return x.MaxKeysPerSec
+	}
+	return 0
+}
+
+func (x *Target) GetBatchPriority() bool {
+	if x != nil {
+		return x.BatchPriority
+	}
+	return false
+}
+
+func (x *Target) GetTransactionTag() string {
+	if x != nil {
+		return x.TransactionTag
+	}
+	return ""
+}
+
+func (x *Target) GetBackoff() bool {
+	if x != nil {
+		return x.Backoff
+	}
+	return false
+}
+
+func (x *Target) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+func (x *Target) GetEncrypt() bool {
+	if x != nil {
+		return x.Encrypt
+	}
+	return false
+}
+
+func (x *Target) GetMaxFileSize() int64 {
+	if x != nil {
+		return x.MaxFileSize
+	}
+	return 0
+}
+
+func (x *Target) GetSampleBy() string {
+	if x != nil {
+		return x.SampleBy
+	}
+	return ""
+}
+
+func (x *Target) GetSampleSeed() string {
+	if x != nil {
+		return x.SampleSeed
+	}
+	return ""
+}
+
+func (x *Target) GetRedact() []*RedactRule {
+	if x != nil {
+		return x.Redact
+	}
+	return nil
+}
+
+func (x *Target) GetLockAware() bool {
+	if x != nil {
+		return x.LockAware
+	}
+	return false
+}
+
+func (x *Target) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+type PrefixRemap struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From []byte `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To   []byte `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+}
+
+func (x *PrefixRemap) Reset() {
+	*x = PrefixRemap{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ferry_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PrefixRemap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefixRemap) ProtoMessage() {}
+
+func (x *PrefixRemap) ProtoReflect() protoreflect.Message {
+	mi := &file_ferry_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefixRemap.ProtoReflect.Descriptor instead.
+func (*PrefixRemap) Descriptor() ([]byte, []int) {
+	return file_ferry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PrefixRemap) GetFrom() []byte {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *PrefixRemap) GetTo() []byte {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type RedactRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Prefix  []byte `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`    // keys starting with this
+	Action  string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`    // drop|null|hash|tokenize|replace
+	Element int32  `protobuf:"varint,3,opt,name=element,proto3" json:"element,omitempty"` // 1-based tuple element of the value, 0 = the whole value
+	Value   []byte `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`      // for replace
+}
+
+func (x *RedactRule) Reset() {
+	*x = RedactRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_ferry_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RedactRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedactRule) ProtoMessage() {}
+
+func (x *RedactRule) ProtoReflect() protoreflect.Message {
+	mi := &file_ferry_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedactRule.ProtoReflect.Descriptor instead.
+func (*RedactRule) Descriptor() ([]byte, []int) {
+	return file_ferry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RedactRule) GetPrefix() []byte {
+	if x != nil {
+		return x.Prefix
+	}
+	return nil
+}
+
+func (x *RedactRule) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *RedactRule) GetElement() int32 {
+	if x != nil {
+		return x.Element
+	}
+	return 0
+}
+
+func (x *RedactRule) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
 type KeyRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -484,7 +754,7 @@ type KeyRequest struct {
 func (x *KeyRequest) Reset() {
 	*x = KeyRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ferry_proto_msgTypes[5]
+		mi := &file_ferry_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -497,7 +767,7 @@ func (x *KeyRequest) String() string {
 func (*KeyRequest) ProtoMessage() {}
 
 func (x *KeyRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_ferry_proto_msgTypes[5]
+	mi := &file_ferry_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -510,7 +780,7 @@ func (x *KeyRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use KeyRequest.ProtoReflect.Descriptor instead.
 func (*KeyRequest) Descriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{5}
+	return file_ferry_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *KeyRequest) GetBegin() []byte {
@@ -539,18 +809,23 @@ type KeyRangeResponse struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	BeginKey  []byte                    `protobuf:"bytes,1,opt,name=begin_key,json=beginKey,proto3" json:"begin_key,omitempty"`
-	EndKey    []byte                    `protobuf:"bytes,2,opt,name=end_key,json=endKey,proto3" json:"end_key,omitempty"`
-	StartTime int64                     `protobuf:"varint,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
-	EndTime   int64                     `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
-	Status    KeyRangeResponse_OpStatus `protobuf:"varint,5,opt,name=status,proto3,enum=ferry.KeyRangeResponse_OpStatus" json:"status,omitempty"`
-	FileUrl   string                    `protobuf:"bytes,6,opt,name=file_url,json=fileUrl,proto3" json:"file_url,omitempty"` // only set on success
+	BeginKey     []byte                    `protobuf:"bytes,1,opt,name=begin_key,json=beginKey,proto3" json:"begin_key,omitempty"`
+	EndKey       []byte                    `protobuf:"bytes,2,opt,name=end_key,json=endKey,proto3" json:"end_key,omitempty"`
+	StartTime    int64                     `protobuf:"varint,3,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime      int64                     `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Status       KeyRangeResponse_OpStatus `protobuf:"varint,5,opt,name=status,proto3,enum=ferry.KeyRangeResponse_OpStatus" json:"status,omitempty"`
+	FileUrl      string                    `protobuf:"bytes,6,opt,name=file_url,json=fileUrl,proto3" json:"file_url,omitempty"`                // only set on success
+	ErrorDetails string                    `protobuf:"bytes,7,opt,name=error_details,json=errorDetails,proto3" json:"error_details,omitempty"` // only set on failure
+	RowCount     int64                     `protobuf:"varint,8,opt,name=row_count,json=rowCount,proto3" json:"row_count,omitempty"`
+	Bytes        int64                     `protobuf:"varint,9,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Retries      int32                     `protobuf:"varint,10,opt,name=retries,proto3" json:"retries,omitempty"` // transactions restarted while reading the range
+	Files        []*FinalizedFile          `protobuf:"bytes,11,rep,name=files,proto3" json:"files,omitempty"`      // only set on success, the files the range went to
 }
 
 func (x *KeyRangeResponse) Reset() {
 	*x = KeyRangeResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ferry_proto_msgTypes[6]
+		mi := &file_ferry_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -563,7 +838,7 @@ func (x *KeyRangeResponse) String() string {
 func (*KeyRangeResponse) ProtoMessage() {}
 
 func (x *KeyRangeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ferry_proto_msgTypes[6]
+	mi := &file_ferry_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -576,7 +851,7 @@ func (x *KeyRangeResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use KeyRangeResponse.ProtoReflect.Descriptor instead.
 func (*KeyRangeResponse) Descriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{6}
+	return file_ferry_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *KeyRangeResponse) GetBeginKey() []byte {
@@ -621,23 +896,67 @@ func (x *KeyRangeResponse) GetFileUrl() string {
 	return ""
 }
 
+func (x *KeyRangeResponse) GetErrorDetails() string {
+	if x != nil {
+		return x.ErrorDetails
+	}
+	return ""
+}
+
+func (x *KeyRangeResponse) GetRowCount() int64 {
+	if x != nil {
+		return x.RowCount
+	}
+	return 0
+}
+
+func (x *KeyRangeResponse) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *KeyRangeResponse) GetRetries() int32 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *KeyRangeResponse) GetFiles() []*FinalizedFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
 type FinalizedFile struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	FileName    string `protobuf:"bytes,1,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
-	KeyRange    string `protobuf:"bytes,2,opt,name=key_range,json=keyRange,proto3" json:"key_range,omitempty"`
-	Checksum    string `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
-	ContentSize int64  `protobuf:"varint,4,opt,name=content_size,json=contentSize,proto3" json:"content_size,omitempty"`
-	RowCount    int64  `protobuf:"varint,5,opt,name=row_count,json=rowCount,proto3" json:"row_count,omitempty"`
-	ShellOnly   bool   `protobuf:"varint,6,opt,name=shell_only,json=shellOnly,proto3" json:"shell_only,omitempty"`
+	FileName      string `protobuf:"bytes,1,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	KeyRange      string `protobuf:"bytes,2,opt,name=key_range,json=keyRange,proto3" json:"key_range,omitempty"`
+	Checksum      string `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	ContentSize   int64  `protobuf:"varint,4,opt,name=content_size,json=contentSize,proto3" json:"content_size,omitempty"`
+	RowCount      int64  `protobuf:"varint,5,opt,name=row_count,json=rowCount,proto3" json:"row_count,omitempty"`
+	ShellOnly     bool   `protobuf:"varint,6,opt,name=shell_only,json=shellOnly,proto3" json:"shell_only,omitempty"`
+	BeginKey      []byte `protobuf:"bytes,7,opt,name=begin_key,json=beginKey,proto3" json:"begin_key,omitempty"`
+	EndKey        []byte `protobuf:"bytes,8,opt,name=end_key,json=endKey,proto3" json:"end_key,omitempty"`
+	StartTime     int64  `protobuf:"varint,9,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"` // unix nano
+	EndTime       int64  `protobuf:"varint,10,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`      // unix nano
+	Compression   string `protobuf:"bytes,11,opt,name=compression,proto3" json:"compression,omitempty"`
+	FormatVersion int32  `protobuf:"varint,12,opt,name=format_version,json=formatVersion,proto3" json:"format_version,omitempty"`
+	ExportFormat  string `protobuf:"bytes,13,opt,name=export_format,json=exportFormat,proto3" json:"export_format,omitempty"`
+	KeyId         string `protobuf:"bytes,14,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // encryption key, empty if not encrypted
+	Host          string `protobuf:"bytes,15,opt,name=host,proto3" json:"host,omitempty"`                // node that wrote it, only set in the journal
 }
 
 func (x *FinalizedFile) Reset() {
 	*x = FinalizedFile{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ferry_proto_msgTypes[7]
+		mi := &file_ferry_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -650,7 +969,7 @@ func (x *FinalizedFile) String() string {
 func (*FinalizedFile) ProtoMessage() {}
 
 func (x *FinalizedFile) ProtoReflect() protoreflect.Message {
-	mi := &file_ferry_proto_msgTypes[7]
+	mi := &file_ferry_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -663,7 +982,7 @@ func (x *FinalizedFile) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FinalizedFile.ProtoReflect.Descriptor instead.
 func (*FinalizedFile) Descriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{7}
+	return file_ferry_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *FinalizedFile) GetFileName() string {
@@ -708,6 +1027,69 @@ func (x *FinalizedFile) GetShellOnly() bool {
 	return false
 }
 
+func (x *FinalizedFile) GetBeginKey() []byte {
+	if x != nil {
+		return x.BeginKey
+	}
+	return nil
+}
+
+func (x *FinalizedFile) GetEndKey() []byte {
+	if x != nil {
+		return x.EndKey
+	}
+	return nil
+}
+
+func (x *FinalizedFile) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *FinalizedFile) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+func (x *FinalizedFile) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+func (x *FinalizedFile) GetFormatVersion() int32 {
+	if x != nil {
+		return x.FormatVersion
+	}
+	return 0
+}
+
+func (x *FinalizedFile) GetExportFormat() string {
+	if x != nil {
+		return x.ExportFormat
+	}
+	return ""
+}
+
+func (x *FinalizedFile) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *FinalizedFile) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
 type SessionResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -718,12 +1100,13 @@ type SessionResponse struct {
 	SessionId      string                       `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                 // session_id for the app level session
 	ErrorDetails   string                       `protobuf:"bytes,4,opt,name=error_details,json=errorDetails,proto3" json:"error_details,omitempty"`        // only set on success
 	FinalizedFiles []*FinalizedFile             `protobuf:"bytes,5,rep,name=finalized_files,json=finalizedFiles,proto3" json:"finalized_files,omitempty"`
+	Ranges         []*KeyRangeResponse          `protobuf:"bytes,6,rep,name=ranges,proto3" json:"ranges,omitempty"` // outcome of every range sent
 }
 
 func (x *SessionResponse) Reset() {
 	*x = SessionResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ferry_proto_msgTypes[8]
+		mi := &file_ferry_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -736,7 +1119,7 @@ func (x *SessionResponse) String() string {
 func (*SessionResponse) ProtoMessage() {}
 
 func (x *SessionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ferry_proto_msgTypes[8]
+	mi := &file_ferry_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -749,7 +1132,7 @@ func (x *SessionResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionResponse.ProtoReflect.Descriptor instead.
 func (*SessionResponse) Descriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{8}
+	return file_ferry_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *SessionResponse) GetStatus() SessionResponse_OpStatus {
@@ -787,6 +1170,13 @@ func (x *SessionResponse) GetFinalizedFiles() []*FinalizedFile {
 	return nil
 }
 
+func (x *SessionResponse) GetRanges() []*KeyRangeResponse {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
 type Session struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -798,7 +1188,7 @@ type Session struct {
 func (x *Session) Reset() {
 	*x = Session{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_ferry_proto_msgTypes[9]
+		mi := &file_ferry_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -811,7 +1201,7 @@ func (x *Session) String() string {
 func (*Session) ProtoMessage() {}
 
 func (x *Session) ProtoReflect() protoreflect.Message {
-	mi := &file_ferry_proto_msgTypes[9]
+	mi := &file_ferry_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -824,7 +1214,7 @@ func (x *Session) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Session.ProtoReflect.Descriptor instead.
 func (*Session) Descriptor() ([]byte, []int) {
-	return file_ferry_proto_rawDescGZIP(), []int{9}
+	return file_ferry_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *Session) GetSessionId() string {
@@ -857,8 +1247,8 @@ var file_ferry_proto_rawDesc = []byte{
 	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63,
 	0x6b, 0x44, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x6c, 0x6f,
 	0x63, 0x6b, 0x44, 0x61, 0x74, 0x61, 0x22, 0x16, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x0e,
-	0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x22, 0xb2,
-	0x01, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x61, 0x72,
+	0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x22, 0x8e,
+	0x06, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x61, 0x72,
 	0x67, 0x65, 0x74, 0x5f, 0x75, 0x72, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74,
 	0x61, 0x72, 0x67, 0x65, 0x74, 0x55, 0x72, 0x6c, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x61, 0x64,
 	0x65, 0x72, 0x5f, 0x74, 0x68, 0x72, 0x65, 0x61, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
@@ -869,105 +1259,195 @@ var file_ferry_proto_rawDesc = []byte{
 	0x05, 0x52, 0x0b, 0x72, 0x65, 0x61, 0x64, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x12, 0x23,
 	0x0a, 0x0d, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18,
 	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x46, 0x6f, 0x72,
-	0x6d, 0x61, 0x74, 0x22, 0x53, 0x0a, 0x0a, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x65, 0x67, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x05, 0x62, 0x65, 0x67, 0x69, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73,
-	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x8c, 0x02, 0x0a, 0x10, 0x4b, 0x65, 0x79,
-	0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a,
-	0x09, 0x62, 0x65, 0x67, 0x69, 0x6e, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x08, 0x62, 0x65, 0x67, 0x69, 0x6e, 0x4b, 0x65, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e,
-	0x64, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x65, 0x6e, 0x64,
-	0x4b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d,
-	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69,
-	0x6d, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x38, 0x0a,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x20, 0x2e,
+	0x6d, 0x61, 0x74, 0x12, 0x15, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x61,
+	0x74, 0x63, 0x68, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x62, 0x61, 0x74, 0x63, 0x68, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x62,
+	0x61, 0x74, 0x63, 0x68, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x09, 0x62, 0x61, 0x74, 0x63, 0x68, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x6e,
+	0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x69, 0x63, 0x74, 0x12, 0x28, 0x0a, 0x05, 0x72,
+	0x65, 0x6d, 0x61, 0x70, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x66, 0x65, 0x72,
+	0x72, 0x79, 0x2e, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x52, 0x65, 0x6d, 0x61, 0x70, 0x52, 0x05,
+	0x72, 0x65, 0x6d, 0x61, 0x70, 0x12, 0x29, 0x0a, 0x11, 0x6d, 0x61, 0x78, 0x5f, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0e, 0x6d, 0x61, 0x78, 0x42, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63,
+	0x12, 0x27, 0x0a, 0x10, 0x6d, 0x61, 0x78, 0x5f, 0x6b, 0x65, 0x79, 0x73, 0x5f, 0x70, 0x65, 0x72,
+	0x5f, 0x73, 0x65, 0x63, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6d, 0x61, 0x78, 0x4b,
+	0x65, 0x79, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63, 0x12, 0x25, 0x0a, 0x0e, 0x62, 0x61, 0x74,
+	0x63, 0x68, 0x5f, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x0d, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0d, 0x62, 0x61, 0x74, 0x63, 0x68, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79,
+	0x12, 0x27, 0x0a, 0x0f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x74, 0x61, 0x67, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x61, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x61, 0x63,
+	0x6b, 0x6f, 0x66, 0x66, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x62, 0x61, 0x63, 0x6b,
+	0x6f, 0x66, 0x66, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74,
+	0x18, 0x11, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x12,
+	0x22, 0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65,
+	0x18, 0x12, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x46, 0x69, 0x6c, 0x65, 0x53,
+	0x69, 0x7a, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x62, 0x79,
+	0x18, 0x13, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x42, 0x79,
+	0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x5f, 0x73, 0x65, 0x65, 0x64, 0x18,
+	0x14, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x53, 0x65, 0x65,
+	0x64, 0x12, 0x29, 0x0a, 0x06, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x18, 0x15, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x52, 0x65, 0x64, 0x61, 0x63, 0x74,
+	0x52, 0x75, 0x6c, 0x65, 0x52, 0x06, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x61, 0x77, 0x61, 0x72, 0x65, 0x18, 0x16, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x09, 0x6c, 0x6f, 0x63, 0x6b, 0x41, 0x77, 0x61, 0x72, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x68,
+	0x6f, 0x73, 0x74, 0x18, 0x17, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x22,
+	0x31, 0x0a, 0x0b, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x52, 0x65, 0x6d, 0x61, 0x70, 0x12, 0x12,
+	0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x66, 0x72,
+	0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02,
+	0x74, 0x6f, 0x22, 0x6c, 0x0a, 0x0a, 0x52, 0x65, 0x64, 0x61, 0x63, 0x74, 0x52, 0x75, 0x6c, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x18, 0x0a, 0x07, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x07, 0x65, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x22, 0x53, 0x0a, 0x0a, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x62, 0x65, 0x67, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62,
+	0x65, 0x67, 0x69, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0xaa, 0x03, 0x0a, 0x10, 0x4b, 0x65, 0x79, 0x52, 0x61, 0x6e,
+	0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x65,
+	0x67, 0x69, 0x6e, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x62,
+	0x65, 0x67, 0x69, 0x6e, 0x4b, 0x65, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6b,
+	0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4b, 0x65, 0x79,
+	0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12,
+	0x19, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x38, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x20, 0x2e, 0x66, 0x65, 0x72,
+	0x72, 0x79, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x2e, 0x4f, 0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x75, 0x72, 0x6c,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x66, 0x69, 0x6c, 0x65, 0x55, 0x72, 0x6c, 0x12,
+	0x23, 0x0a, 0x0d, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x44, 0x65, 0x74,
+	0x61, 0x69, 0x6c, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x6f, 0x77, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x72, 0x6f, 0x77, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x74, 0x72, 0x69,
+	0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x72, 0x65, 0x74, 0x72, 0x69, 0x65,
+	0x73, 0x12, 0x2a, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a,
+	0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x33, 0x0a,
+	0x08, 0x4f, 0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55, 0x43,
+	0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x41, 0x49, 0x4c, 0x55, 0x52,
+	0x45, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x49, 0x45, 0x4e, 0x54,
+	0x10, 0x02, 0x22, 0xcd, 0x03, 0x0a, 0x0d, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64,
+	0x46, 0x69, 0x6c, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6b, 0x65, 0x79, 0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6b, 0x65, 0x79, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x1a,
+	0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1b, 0x0a,
+	0x09, 0x72, 0x6f, 0x77, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x72, 0x6f, 0x77, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x68,
+	0x65, 0x6c, 0x6c, 0x5f, 0x6f, 0x6e, 0x6c, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09,
+	0x73, 0x68, 0x65, 0x6c, 0x6c, 0x4f, 0x6e, 0x6c, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x65, 0x67,
+	0x69, 0x6e, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x62, 0x65,
+	0x67, 0x69, 0x6e, 0x4b, 0x65, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6b, 0x65,
+	0x79, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4b, 0x65, 0x79, 0x12,
+	0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x19,
+	0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6d,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x0e, 0x66,
+	0x6f, 0x72, 0x6d, 0x61, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0c, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x0d, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x5f, 0x66, 0x6f, 0x72,
+	0x6d, 0x61, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x78, 0x70, 0x6f, 0x72,
+	0x74, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79, 0x5f, 0x69,
+	0x64, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f,
+	0x73, 0x74, 0x22, 0x94, 0x03, 0x0a, 0x0f, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4f,
+	0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x39, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x23,
+	0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x5f, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12, 0x3d,
+	0x0a, 0x0f, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e,
+	0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0e, 0x66,
+	0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x2f, 0x0a,
+	0x06, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e,
 	0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4f, 0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x5f,
-	0x75, 0x72, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x66, 0x69, 0x6c, 0x65, 0x55,
-	0x72, 0x6c, 0x22, 0x33, 0x0a, 0x08, 0x4f, 0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b,
-	0x0a, 0x07, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x46,
-	0x41, 0x49, 0x4c, 0x55, 0x52, 0x45, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x52, 0x41, 0x4e,
-	0x53, 0x49, 0x45, 0x4e, 0x54, 0x10, 0x02, 0x22, 0xc4, 0x01, 0x0a, 0x0d, 0x46, 0x69, 0x6e, 0x61,
-	0x6c, 0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x69, 0x6c,
-	0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69,
-	0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6b, 0x65, 0x79, 0x5f, 0x72, 0x61,
-	0x6e, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6b, 0x65, 0x79, 0x52, 0x61,
-	0x6e, 0x67, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12,
-	0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x53, 0x69,
-	0x7a, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x6f, 0x77, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x72, 0x6f, 0x77, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12,
-	0x1d, 0x0a, 0x0a, 0x73, 0x68, 0x65, 0x6c, 0x6c, 0x5f, 0x6f, 0x6e, 0x6c, 0x79, 0x18, 0x06, 0x20,
-	0x01, 0x28, 0x08, 0x52, 0x09, 0x73, 0x68, 0x65, 0x6c, 0x6c, 0x4f, 0x6e, 0x6c, 0x79, 0x22, 0xe3,
-	0x02, 0x0a, 0x0f, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x12, 0x37, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69,
-	0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4f, 0x70, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x39, 0x0a, 0x05, 0x73,
-	0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x23, 0x2e, 0x66, 0x65, 0x72,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x06, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x22, 0x24,
+	0x0a, 0x08, 0x4f, 0x70, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55,
+	0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x41, 0x49, 0x4c, 0x55,
+	0x52, 0x45, 0x10, 0x01, 0x22, 0x33, 0x0a, 0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x54, 0x41, 0x52, 0x54, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x54, 0x4f, 0x50, 0x50, 0x45, 0x44, 0x10, 0x01, 0x12, 0x09,
+	0x0a, 0x05, 0x45, 0x4e, 0x44, 0x45, 0x44, 0x10, 0x02, 0x22, 0x28, 0x0a, 0x07, 0x53, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x49, 0x64, 0x32, 0x81, 0x06, 0x0a, 0x05, 0x46, 0x65, 0x72, 0x72, 0x79, 0x12, 0x3d, 0x0a,
+	0x12, 0x53, 0x74, 0x61, 0x72, 0x74, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x0d, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x54, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x06,
+	0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x11, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x4b,
+	0x65, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72,
+	0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3d, 0x0a, 0x11, 0x53, 0x74, 0x6f, 0x70, 0x45, 0x78, 0x70,
+	0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66, 0x65, 0x72,
+	0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72,
 	0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
-	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f,
-	0x6e, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x64,
-	0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x72,
-	0x72, 0x6f, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12, 0x3d, 0x0a, 0x0f, 0x66, 0x69,
-	0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x05, 0x20,
-	0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6e, 0x61,
-	0x6c, 0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0e, 0x66, 0x69, 0x6e, 0x61, 0x6c,
-	0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x24, 0x0a, 0x08, 0x4f, 0x70, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53, 0x53,
-	0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x41, 0x49, 0x4c, 0x55, 0x52, 0x45, 0x10, 0x01, 0x22,
-	0x33, 0x0a, 0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12,
-	0x0b, 0x0a, 0x07, 0x53, 0x54, 0x41, 0x52, 0x54, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07,
-	0x53, 0x54, 0x4f, 0x50, 0x50, 0x45, 0x44, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x4e, 0x44,
-	0x45, 0x44, 0x10, 0x02, 0x22, 0x28, 0x0a, 0x07, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12,
-	0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x32, 0xbd,
-	0x04, 0x0a, 0x05, 0x46, 0x65, 0x72, 0x72, 0x79, 0x12, 0x3d, 0x0a, 0x12, 0x53, 0x74, 0x61, 0x72,
-	0x74, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0d,
-	0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x1a, 0x16, 0x2e,
-	0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x06, 0x45, 0x78, 0x70, 0x6f, 0x72,
-	0x74, 0x12, 0x11, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01,
-	0x12, 0x3d, 0x0a, 0x11, 0x53, 0x74, 0x6f, 0x70, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65,
-	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65,
-	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65,
-	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
-	0x45, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x46, 0x69,
-	0x6c, 0x65, 0x12, 0x12, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46,
-	0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3e, 0x0a, 0x12, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65,
-	0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x2e, 0x66,
-	0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x12, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x22, 0x00, 0x12, 0x3c, 0x0a, 0x10, 0x45, 0x6e, 0x64, 0x45, 0x78, 0x70,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x45, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x45, 0x78, 0x70, 0x6f, 0x72,
+	0x74, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x66, 0x65,
+	0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3e, 0x0a, 0x12, 0x52,
+	0x65, 0x6d, 0x6f, 0x76, 0x65, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x46, 0x69, 0x6c,
+	0x65, 0x12, 0x12, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x46, 0x69,
+	0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x00, 0x12, 0x3c, 0x0a, 0x10, 0x45,
+	0x6e, 0x64, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x0e, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a,
+	0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x12, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x45, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x0e, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a,
+	0x17, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x61, 0x6e, 0x67, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3d, 0x0a, 0x12,
+	0x53, 0x74, 0x61, 0x72, 0x74, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x0d, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x06, 0x49,
+	0x6d, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x49, 0x6d,
+	0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65,
+	0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3d, 0x0a, 0x11, 0x53, 0x74, 0x6f, 0x70, 0x49,
+	0x6d, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66,
+	0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x66,
+	0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3c, 0x0a, 0x10, 0x45, 0x6e, 0x64, 0x49, 0x6d, 0x70,
 	0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66, 0x65, 0x72,
 	0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72,
 	0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x12, 0x53, 0x74, 0x61, 0x72, 0x74, 0x49, 0x6d, 0x70,
-	0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0d, 0x2e, 0x66, 0x65, 0x72,
-	0x72, 0x79, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72,
-	0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x06, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x14, 0x2e,
-	0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x12,
-	0x3d, 0x0a, 0x11, 0x53, 0x74, 0x6f, 0x70, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2e, 0x53, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x22,
-	0x5a, 0x20, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x64, 0x6f,
-	0x62, 0x65, 0x2f, 0x66, 0x65, 0x72, 0x72, 0x79, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x66, 0x65, 0x72,
-	0x72, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x12, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x70,
+	0x6f, 0x72, 0x74, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x2e, 0x66, 0x65, 0x72,
+	0x72, 0x79, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x17, 0x2e, 0x66, 0x65, 0x72,
+	0x72, 0x79, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x22, 0x5a, 0x20, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x64, 0x6f, 0x62, 0x65, 0x2f, 0x66, 0x65, 0x72, 0x72,
+	0x79, 0x2f, 0x72, 0x70, 0x63, 0x2f, 0x66, 0x65, 0x72, 0x72, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -983,7 +1463,7 @@ func file_ferry_proto_rawDescGZIP() []byte {
 }
 
 var file_ferry_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_ferry_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_ferry_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_ferry_proto_goTypes = []interface{}{
 	(KeyRangeResponse_OpStatus)(0),    // 0: ferry.KeyRangeResponse.OpStatus
 	(SessionResponse_OpStatus)(0),     // 1: ferry.SessionResponse.OpStatus
@@ -993,40 +1473,52 @@ var file_ferry_proto_goTypes = []interface{}{
 	(*FileRequestResponse)(nil),       // 5: ferry.FileRequestResponse
 	(*Time)(nil),                      // 6: ferry.Time
 	(*Target)(nil),                    // 7: ferry.Target
-	(*KeyRequest)(nil),                // 8: ferry.KeyRequest
-	(*KeyRangeResponse)(nil),          // 9: ferry.KeyRangeResponse
-	(*FinalizedFile)(nil),             // 10: ferry.FinalizedFile
-	(*SessionResponse)(nil),           // 11: ferry.SessionResponse
-	(*Session)(nil),                   // 12: ferry.Session
+	(*PrefixRemap)(nil),               // 8: ferry.PrefixRemap
+	(*RedactRule)(nil),                // 9: ferry.RedactRule
+	(*KeyRequest)(nil),                // 10: ferry.KeyRequest
+	(*KeyRangeResponse)(nil),          // 11: ferry.KeyRangeResponse
+	(*FinalizedFile)(nil),             // 12: ferry.FinalizedFile
+	(*SessionResponse)(nil),           // 13: ferry.SessionResponse
+	(*Session)(nil),                   // 14: ferry.Session
 }
 var file_ferry_proto_depIdxs = []int32{
-	0,  // 0: ferry.KeyRangeResponse.status:type_name -> ferry.KeyRangeResponse.OpStatus
-	1,  // 1: ferry.SessionResponse.status:type_name -> ferry.SessionResponse.OpStatus
-	2,  // 2: ferry.SessionResponse.state:type_name -> ferry.SessionResponse.SessionState
-	10, // 3: ferry.SessionResponse.finalized_files:type_name -> ferry.FinalizedFile
-	7,  // 4: ferry.Ferry.StartExportSession:input_type -> ferry.Target
-	8,  // 5: ferry.Ferry.Export:input_type -> ferry.KeyRequest
-	12, // 6: ferry.Ferry.StopExportSession:input_type -> ferry.Session
-	4,  // 7: ferry.Ferry.GetExportedFile:input_type -> ferry.FileRequest
-	4,  // 8: ferry.Ferry.RemoveExportedFile:input_type -> ferry.FileRequest
-	12, // 9: ferry.Ferry.EndExportSession:input_type -> ferry.Session
-	7,  // 10: ferry.Ferry.StartImportSession:input_type -> ferry.Target
-	3,  // 11: ferry.Ferry.Import:input_type -> ferry.ImportRequest
-	12, // 12: ferry.Ferry.StopImportSession:input_type -> ferry.Session
-	11, // 13: ferry.Ferry.StartExportSession:output_type -> ferry.SessionResponse
-	11, // 14: ferry.Ferry.Export:output_type -> ferry.SessionResponse
-	11, // 15: ferry.Ferry.StopExportSession:output_type -> ferry.SessionResponse
-	5,  // 16: ferry.Ferry.GetExportedFile:output_type -> ferry.FileRequestResponse
-	4,  // 17: ferry.Ferry.RemoveExportedFile:output_type -> ferry.FileRequest
-	11, // 18: ferry.Ferry.EndExportSession:output_type -> ferry.SessionResponse
-	11, // 19: ferry.Ferry.StartImportSession:output_type -> ferry.SessionResponse
-	11, // 20: ferry.Ferry.Import:output_type -> ferry.SessionResponse
-	11, // 21: ferry.Ferry.StopImportSession:output_type -> ferry.SessionResponse
-	13, // [13:22] is the sub-list for method output_type
-	4,  // [4:13] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	8,  // 0: ferry.Target.remap:type_name -> ferry.PrefixRemap
+	9,  // 1: ferry.Target.redact:type_name -> ferry.RedactRule
+	0,  // 2: ferry.KeyRangeResponse.status:type_name -> ferry.KeyRangeResponse.OpStatus
+	12, // 3: ferry.KeyRangeResponse.files:type_name -> ferry.FinalizedFile
+	1,  // 4: ferry.SessionResponse.status:type_name -> ferry.SessionResponse.OpStatus
+	2,  // 5: ferry.SessionResponse.state:type_name -> ferry.SessionResponse.SessionState
+	12, // 6: ferry.SessionResponse.finalized_files:type_name -> ferry.FinalizedFile
+	11, // 7: ferry.SessionResponse.ranges:type_name -> ferry.KeyRangeResponse
+	7,  // 8: ferry.Ferry.StartExportSession:input_type -> ferry.Target
+	10, // 9: ferry.Ferry.Export:input_type -> ferry.KeyRequest
+	14, // 10: ferry.Ferry.StopExportSession:input_type -> ferry.Session
+	4,  // 11: ferry.Ferry.GetExportedFile:input_type -> ferry.FileRequest
+	4,  // 12: ferry.Ferry.RemoveExportedFile:input_type -> ferry.FileRequest
+	14, // 13: ferry.Ferry.EndExportSession:input_type -> ferry.Session
+	14, // 14: ferry.Ferry.WatchExportSession:input_type -> ferry.Session
+	7,  // 15: ferry.Ferry.StartImportSession:input_type -> ferry.Target
+	3,  // 16: ferry.Ferry.Import:input_type -> ferry.ImportRequest
+	14, // 17: ferry.Ferry.StopImportSession:input_type -> ferry.Session
+	14, // 18: ferry.Ferry.EndImportSession:input_type -> ferry.Session
+	14, // 19: ferry.Ferry.WatchImportSession:input_type -> ferry.Session
+	13, // 20: ferry.Ferry.StartExportSession:output_type -> ferry.SessionResponse
+	13, // 21: ferry.Ferry.Export:output_type -> ferry.SessionResponse
+	13, // 22: ferry.Ferry.StopExportSession:output_type -> ferry.SessionResponse
+	5,  // 23: ferry.Ferry.GetExportedFile:output_type -> ferry.FileRequestResponse
+	4,  // 24: ferry.Ferry.RemoveExportedFile:output_type -> ferry.FileRequest
+	13, // 25: ferry.Ferry.EndExportSession:output_type -> ferry.SessionResponse
+	11, // 26: ferry.Ferry.WatchExportSession:output_type -> ferry.KeyRangeResponse
+	13, // 27: ferry.Ferry.StartImportSession:output_type -> ferry.SessionResponse
+	13, // 28: ferry.Ferry.Import:output_type -> ferry.SessionResponse
+	13, // 29: ferry.Ferry.StopImportSession:output_type -> ferry.SessionResponse
+	13, // 30: ferry.Ferry.EndImportSession:output_type -> ferry.SessionResponse
+	11, // 31: ferry.Ferry.WatchImportSession:output_type -> ferry.KeyRangeResponse
+	20, // [20:32] is the sub-list for method output_type
+	8,  // [8:20] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_ferry_proto_init() }
@@ -1096,7 +1588,7 @@ func file_ferry_proto_init() {
 			}
 		}
 		file_ferry_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*KeyRequest); i {
+			switch v := v.(*PrefixRemap); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1108,7 +1600,7 @@ func file_ferry_proto_init() {
 			}
 		}
 		file_ferry_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*KeyRangeResponse); i {
+			switch v := v.(*RedactRule); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1120,7 +1612,7 @@ func file_ferry_proto_init() {
 			}
 		}
 		file_ferry_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FinalizedFile); i {
+			switch v := v.(*KeyRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1132,7 +1624,7 @@ func file_ferry_proto_init() {
 			}
 		}
 		file_ferry_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SessionResponse); i {
+			switch v := v.(*KeyRangeResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1144,6 +1636,30 @@ func file_ferry_proto_init() {
 			}
 		}
 		file_ferry_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FinalizedFile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_ferry_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_ferry_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Session); i {
 			case 0:
 				return &v.state
@@ -1162,7 +1678,7 @@ func file_ferry_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_ferry_proto_rawDesc,
 			NumEnums:      3,
-			NumMessages:   10,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
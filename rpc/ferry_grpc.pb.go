@@ -28,9 +28,12 @@ type FerryClient interface {
 	GetExportedFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (Ferry_GetExportedFileClient, error)
 	RemoveExportedFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*FileRequest, error)
 	EndExportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (*SessionResponse, error)
+	WatchExportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (Ferry_WatchExportSessionClient, error)
 	StartImportSession(ctx context.Context, in *Target, opts ...grpc.CallOption) (*SessionResponse, error)
 	Import(ctx context.Context, opts ...grpc.CallOption) (Ferry_ImportClient, error)
 	StopImportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (*SessionResponse, error)
+	EndImportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (*SessionResponse, error)
+	WatchImportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (Ferry_WatchImportSessionClient, error)
 }
 
 type ferryClient struct {
@@ -143,6 +146,38 @@ func (c *ferryClient) EndExportSession(ctx context.Context, in *Session, opts ..
 	return out, nil
 }
 
+func (c *ferryClient) WatchExportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (Ferry_WatchExportSessionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ferry_ServiceDesc.Streams[2], "/ferry.Ferry/WatchExportSession", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ferryWatchExportSessionClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ferry_WatchExportSessionClient interface {
+	Recv() (*KeyRangeResponse, error)
+	grpc.ClientStream
+}
+
+type ferryWatchExportSessionClient struct {
+	grpc.ClientStream
+}
+
+func (x *ferryWatchExportSessionClient) Recv() (*KeyRangeResponse, error) {
+	m := new(KeyRangeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *ferryClient) StartImportSession(ctx context.Context, in *Target, opts ...grpc.CallOption) (*SessionResponse, error) {
 	out := new(SessionResponse)
 	err := c.cc.Invoke(ctx, "/ferry.Ferry/StartImportSession", in, out, opts...)
@@ -153,7 +188,7 @@ func (c *ferryClient) StartImportSession(ctx context.Context, in *Target, opts .
 }
 
 func (c *ferryClient) Import(ctx context.Context, opts ...grpc.CallOption) (Ferry_ImportClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Ferry_ServiceDesc.Streams[2], "/ferry.Ferry/Import", opts...)
+	stream, err := c.cc.NewStream(ctx, &Ferry_ServiceDesc.Streams[3], "/ferry.Ferry/Import", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -195,6 +230,47 @@ func (c *ferryClient) StopImportSession(ctx context.Context, in *Session, opts .
 	return out, nil
 }
 
+func (c *ferryClient) EndImportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (*SessionResponse, error) {
+	out := new(SessionResponse)
+	err := c.cc.Invoke(ctx, "/ferry.Ferry/EndImportSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ferryClient) WatchImportSession(ctx context.Context, in *Session, opts ...grpc.CallOption) (Ferry_WatchImportSessionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ferry_ServiceDesc.Streams[4], "/ferry.Ferry/WatchImportSession", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ferryWatchImportSessionClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ferry_WatchImportSessionClient interface {
+	Recv() (*KeyRangeResponse, error)
+	grpc.ClientStream
+}
+
+type ferryWatchImportSessionClient struct {
+	grpc.ClientStream
+}
+
+func (x *ferryWatchImportSessionClient) Recv() (*KeyRangeResponse, error) {
+	m := new(KeyRangeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // FerryServer is the server API for Ferry service.
 // All implementations must embed UnimplementedFerryServer
 // for forward compatibility
@@ -205,9 +281,12 @@ type FerryServer interface {
 	GetExportedFile(*FileRequest, Ferry_GetExportedFileServer) error
 	RemoveExportedFile(context.Context, *FileRequest) (*FileRequest, error)
 	EndExportSession(context.Context, *Session) (*SessionResponse, error)
+	WatchExportSession(*Session, Ferry_WatchExportSessionServer) error
 	StartImportSession(context.Context, *Target) (*SessionResponse, error)
 	Import(Ferry_ImportServer) error
 	StopImportSession(context.Context, *Session) (*SessionResponse, error)
+	EndImportSession(context.Context, *Session) (*SessionResponse, error)
+	WatchImportSession(*Session, Ferry_WatchImportSessionServer) error
 	mustEmbedUnimplementedFerryServer()
 }
 
@@ -233,6 +312,9 @@ func (UnimplementedFerryServer) RemoveExportedFile(context.Context, *FileRequest
 func (UnimplementedFerryServer) EndExportSession(context.Context, *Session) (*SessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method EndExportSession not implemented")
 }
+func (UnimplementedFerryServer) WatchExportSession(*Session, Ferry_WatchExportSessionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchExportSession not implemented")
+}
 func (UnimplementedFerryServer) StartImportSession(context.Context, *Target) (*SessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StartImportSession not implemented")
 }
@@ -242,6 +324,12 @@ func (UnimplementedFerryServer) Import(Ferry_ImportServer) error {
 func (UnimplementedFerryServer) StopImportSession(context.Context, *Session) (*SessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StopImportSession not implemented")
 }
+func (UnimplementedFerryServer) EndImportSession(context.Context, *Session) (*SessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EndImportSession not implemented")
+}
+func (UnimplementedFerryServer) WatchImportSession(*Session, Ferry_WatchImportSessionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchImportSession not implemented")
+}
 func (UnimplementedFerryServer) mustEmbedUnimplementedFerryServer() {}
 
 // UnsafeFerryServer may be embedded to opt out of forward compatibility for this service.
@@ -374,6 +462,27 @@ func _Ferry_EndExportSession_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ferry_WatchExportSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Session)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FerryServer).WatchExportSession(m, &ferryWatchExportSessionServer{stream})
+}
+
+type Ferry_WatchExportSessionServer interface {
+	Send(*KeyRangeResponse) error
+	grpc.ServerStream
+}
+
+type ferryWatchExportSessionServer struct {
+	grpc.ServerStream
+}
+
+func (x *ferryWatchExportSessionServer) Send(m *KeyRangeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Ferry_StartImportSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Target)
 	if err := dec(in); err != nil {
@@ -436,6 +545,45 @@ func _Ferry_StopImportSession_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ferry_EndImportSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Session)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FerryServer).EndImportSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ferry.Ferry/EndImportSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FerryServer).EndImportSession(ctx, req.(*Session))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ferry_WatchImportSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Session)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FerryServer).WatchImportSession(m, &ferryWatchImportSessionServer{stream})
+}
+
+type Ferry_WatchImportSessionServer interface {
+	Send(*KeyRangeResponse) error
+	grpc.ServerStream
+}
+
+type ferryWatchImportSessionServer struct {
+	grpc.ServerStream
+}
+
+func (x *ferryWatchImportSessionServer) Send(m *KeyRangeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Ferry_ServiceDesc is the grpc.ServiceDesc for Ferry service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -467,6 +615,10 @@ var Ferry_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StopImportSession",
 			Handler:    _Ferry_StopImportSession_Handler,
 		},
+		{
+			MethodName: "EndImportSession",
+			Handler:    _Ferry_EndImportSession_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -479,11 +631,21 @@ var Ferry_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Ferry_GetExportedFile_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchExportSession",
+			Handler:       _Ferry_WatchExportSession_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Import",
 			Handler:       _Ferry_Import_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WatchImportSession",
+			Handler:       _Ferry_WatchImportSession_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "ferry.proto",
 }
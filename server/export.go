@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/adobe/ferry/exporter/session"
+	"github.com/adobe/ferry/format"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
@@ -28,13 +29,46 @@ import (
 
 func (exp *Server) StartExportSession(ctx context.Context, tgt *ferry.Target) (*ferry.SessionResponse, error) {
 
-	es, err := session.NewSession(exp.db,
-		tgt.TargetUrl,
-		int(tgt.ReaderThreads),
-		tgt.Compress,
-		exp.logger,
-		int(tgt.ReadPercent),
-		tgt.ExportFormat)
+	compression := tgt.Compression
+	if compression == "" && tgt.Compress {
+		compression = format.CODEC_LZ4
+	}
+	var key *format.Key
+	if tgt.Encrypt {
+		if exp.encryptionKey == nil {
+			return nil, errors.New("Encryption requested, but no encryption key is configured on this node")
+		}
+		key = exp.encryptionKey
+	}
+	redaction := session.Redaction{Salt: exp.redactSalt}
+	for _, r := range tgt.Redact {
+		redaction.Rules = append(redaction.Rules, session.RedactRule{
+			Prefix:  r.Prefix,
+			Action:  r.Action,
+			Element: int(r.Element),
+			Value:   r.Value,
+		})
+	}
+	es, err := session.NewSession(exp.db, tgt.TargetUrl, exp.logger, session.Options{
+		ReaderThreads: int(tgt.ReaderThreads),
+		Compression:   compression,
+		ReadPercent:   int(tgt.ReadPercent),
+		Sampling:      session.Sampling{By: tgt.SampleBy, Seed: tgt.SampleSeed},
+		ExportFormat:  tgt.ExportFormat,
+		JobID:         tgt.JobId,
+		Throttle: session.Throttle{
+			BytesPerSecond: tgt.MaxBytesPerSec,
+			KeysPerSecond:  tgt.MaxKeysPerSec,
+			BatchPriority:  tgt.BatchPriority,
+			Tag:            tgt.TransactionTag,
+			Backoff:        tgt.Backoff,
+		},
+		Key:         key,
+		MaxFileSize: tgt.MaxFileSize,
+		Redaction:   redaction,
+		LockAware:   tgt.LockAware,
+		Host:        tgt.Host,
+	})
 	if err != nil {
 		exp.logger.Warn("Failed to create a session ID", zap.Error(err))
 		return nil, errors.Wrap(err, "Failed to create a session ID")
@@ -42,6 +76,7 @@ func (exp *Server) StartExportSession(ctx context.Context, tgt *ferry.Target) (*
 
 	sessionID := es.GetSessionID()
 	exp.exportSessions.Store(sessionID, es)
+	exp.exportWatches.Store(sessionID, es)
 	exp.logger.Info("Created session", zap.String("sessionID", sessionID))
 
 	return &ferry.SessionResponse{SessionId: sessionID, Status: ferry.SessionResponse_SUCCESS}, err
@@ -63,6 +98,7 @@ func (exp *Server) Export(srv ferry.Ferry_ExportServer) error {
 			if es != nil {
 				// If `es` is set, it is assumed
 				// to be pop-ed - cleanup resources
+				exp.exportWatches.Delete(currentSessionID)
 				es.Finalize()
 			}
 			return errors.Errorf("Single stream cannot have multiple session ids %s", currentSessionID)
@@ -75,6 +111,7 @@ func (exp *Server) Export(srv ferry.Ferry_ExportServer) error {
 				if es != nil {
 					// If `es` is set, it is assumed
 					// to be pop-ed - cleanup resources
+					exp.exportWatches.Delete(currentSessionID)
 					es.Finalize()
 				}
 				return errors.Errorf("Single stream cannot have multiple session ids %s", currentSessionID)
@@ -141,19 +178,18 @@ func (exp *Server) StopExportSession(ctx context.Context, fs *ferry.Session) (*f
 
 	var protoFinalizedFiles []*ferry.FinalizedFile
 	for k, v := range finalPaths {
-		x := &ferry.FinalizedFile{}
-		x.Checksum = v.Checksum
-		x.RowCount = v.RowsWritten
-		x.ContentSize = v.BytesWritten
-		x.FileName = v.FileName
-		x.KeyRange = k
-		protoFinalizedFiles = append(protoFinalizedFiles, x)
+		protoFinalizedFiles = append(protoFinalizedFiles, v.Proto(k))
+	}
+	var protoRanges []*ferry.KeyRangeResponse
+	for _, r := range es.RangeResults() {
+		protoRanges = append(protoRanges, r.Proto())
 	}
 
 	return &ferry.SessionResponse{
 		SessionId:      fs.SessionId,
 		Status:         ferry.SessionResponse_SUCCESS,
 		FinalizedFiles: protoFinalizedFiles,
+		Ranges:         protoRanges,
 	}, nil
 }
 
@@ -170,19 +206,15 @@ func (exp *Server) EndExportSession(ctx context.Context, fs *ferry.Session) (*fe
 	// Very Import: this (EndSession) is the only situation
 	// a 'pop' is not followed by a
 	// 	"defer exp.sessions.Store(fr.SessionId, es)"
+	exp.exportWatches.Delete(fs.SessionId)
 
 	exp.logger.Debug("Releasing resources", zap.String("sessionID", fs.SessionId))
 	finalPaths := es.Finalize()
 	exp.logger.Info("Released resources", zap.String("sessionID", fs.SessionId))
 
 	var ferryFinalizedFiles []*ferry.FinalizedFile
-	for _, v := range finalPaths {
-		x := &ferry.FinalizedFile{}
-		x.Checksum = v.Checksum
-		x.RowCount = v.ChunksWritten
-		x.ContentSize = v.BytesWritten
-		x.FileName = v.FileName
-		ferryFinalizedFiles = append(ferryFinalizedFiles, x)
+	for k, v := range finalPaths {
+		ferryFinalizedFiles = append(ferryFinalizedFiles, v.Proto(k))
 	}
 
 	return &ferry.SessionResponse{
@@ -192,6 +224,33 @@ func (exp *Server) EndExportSession(ctx context.Context, fs *ferry.Session) (*fe
 	}, nil
 }
 
+// WatchExportSession streams the result of each range as soon as it is
+// done, until the session is stopped. It works alongside the Export stream,
+// so it looks the session up without popping it (Export holds it popped).
+func (exp *Server) WatchExportSession(fs *ferry.Session, srv ferry.Ferry_WatchExportSessionServer) error {
+
+	esi, ok := exp.exportWatches.Load(fs.SessionId)
+	if !ok {
+		return errors.Errorf("Invalid session id - %s", fs.SessionId)
+	}
+	es, ok := esi.(*session.ExporterSession)
+	if !ok {
+		return errors.Errorf("Corrupted tracker for session id %s", fs.SessionId)
+	}
+	exp.logger.Info("Start watch stream", zap.String("sessionID", fs.SessionId))
+	// One watch per session: once it ends (the session ended, or the
+	// client went away) nothing needs to find the session here again
+	defer exp.exportWatches.Delete(fs.SessionId)
+
+	return es.Watch(srv.Context(), func(r session.RangeResult) error {
+		err := srv.Send(r.Proto())
+		if err != nil {
+			return errors.Wrapf(err, "Error from grpc stream send")
+		}
+		return nil
+	})
+}
+
 func (exp *Server) GetExportedFile(fr *ferry.FileRequest, resp ferry.Ferry_GetExportedFileServer) (err error) {
 
 	var es *session.ExporterSession
@@ -14,7 +14,10 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/adobe/ferry/importer/session"
 	ferry "github.com/adobe/ferry/rpc"
@@ -24,18 +27,28 @@ import (
 
 func (exp *Server) StartImportSession(ctx context.Context, tgt *ferry.Target) (*ferry.SessionResponse, error) {
 
+	var remap []session.PrefixRemap
+	for _, r := range tgt.Remap {
+		remap = append(remap, session.PrefixRemap{From: r.From, To: r.To})
+	}
 	es, err := session.NewSession(exp.db,
 		tgt.TargetUrl,
 		int(tgt.ReaderThreads),
 		exp.logger,
-		false)
+		false,
+		int(tgt.BatchBytes),
+		int(tgt.BatchKeys),
+		tgt.OnConflict,
+		remap,
+		exp.encryptionKey)
 	if err != nil {
-		exp.logger.Warn("Failed to create a session ID", zap.Error(err))
-		return nil, errors.Wrap(err, "Failed to create a session ID")
+		exp.logger.Warn("Failed to create a session", zap.Error(err))
+		return nil, errors.Wrap(err, "Failed to create a session")
 	}
 
 	sessionID := es.GetSessionID()
 	exp.importSessions.Store(sessionID, es)
+	exp.importWatches.Store(sessionID, es)
 	exp.logger.Info("Created session", zap.String("sessionID", sessionID))
 
 	return &ferry.SessionResponse{SessionId: sessionID, Status: ferry.SessionResponse_SUCCESS}, err
@@ -57,6 +70,7 @@ func (exp *Server) Import(srv ferry.Ferry_ImportServer) (err error) {
 			if es != nil {
 				// If `es` is set, it is assumed
 				// to be pop-ed - cleanup resources
+				exp.importWatches.Delete(currentSessionID)
 				es.Finalize()
 			}
 			return errors.Errorf("Single stream cannot have multiple session ids %s", currentSessionID)
@@ -69,6 +83,7 @@ func (exp *Server) Import(srv ferry.Ferry_ImportServer) (err error) {
 				if es != nil {
 					// If `es` is set, it is assumed
 					// to be pop-ed - cleanup resources
+					exp.importWatches.Delete(currentSessionID)
 					es.Finalize()
 				}
 				return errors.Errorf("Single stream cannot have multiple session ids %s", currentSessionID)
@@ -134,6 +149,19 @@ func (exp *Server) StopImportSession(ctx context.Context, fs *ferry.Session) (*f
 	es.Finalize()
 	exp.logger.Info("Released resources", zap.String("sessionID", fs.SessionId))
 
+	failures := es.Failures()
+	if len(failures) > 0 {
+		var details []string
+		for fileName, err := range failures {
+			details = append(details, fmt.Sprintf("%s: %v", fileName, err))
+		}
+		sort.Strings(details)
+		return &ferry.SessionResponse{
+			SessionId:    fs.SessionId,
+			Status:       ferry.SessionResponse_FAILURE,
+			ErrorDetails: strings.Join(details, "; "),
+		}, nil
+	}
 	return &ferry.SessionResponse{
 		SessionId: fs.SessionId,
 		Status:    ferry.SessionResponse_SUCCESS,
@@ -153,6 +181,7 @@ func (exp *Server) EndImportSession(ctx context.Context, fs *ferry.Session) (*fe
 	// Very Import: this (EndSession) is the only situation
 	// a 'pop' is not followed by a
 	// 	"defer exp.sessions.Store(fr.SessionId, es)"
+	exp.importWatches.Delete(fs.SessionId)
 
 	exp.logger.Debug("Releasing resources", zap.String("sessionID", fs.SessionId))
 	es.Finalize()
@@ -163,3 +192,30 @@ func (exp *Server) EndImportSession(ctx context.Context, fs *ferry.Session) (*fe
 		Status:    ferry.SessionResponse_SUCCESS,
 	}, nil
 }
+
+// WatchImportSession streams the result of each file as soon as it is
+// imported, until the session is stopped. Like WatchExportSession, it
+// does not pop the session, the Import stream holds it.
+func (exp *Server) WatchImportSession(fs *ferry.Session, srv ferry.Ferry_WatchImportSessionServer) error {
+
+	esi, ok := exp.importWatches.Load(fs.SessionId)
+	if !ok {
+		return errors.Errorf("Invalid session id - %s", fs.SessionId)
+	}
+	es, ok := esi.(*session.ImporterSession)
+	if !ok {
+		return errors.Errorf("Corrupted tracker for session id %s", fs.SessionId)
+	}
+	exp.logger.Info("Start watch stream", zap.String("sessionID", fs.SessionId))
+	// One watch per session: once it ends (the session ended, or the
+	// client went away) nothing needs to find the session here again
+	defer exp.importWatches.Delete(fs.SessionId)
+
+	return es.Watch(srv.Context(), func(r session.FileResult) error {
+		err := srv.Send(r.Proto())
+		if err != nil {
+			return errors.Wrapf(err, "Error from grpc stream send")
+		}
+		return nil
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"net"
 	"sync"
 
+	"github.com/adobe/ferry/format"
 	ferry "github.com/adobe/ferry/rpc"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/pkg/errors"
@@ -30,10 +31,14 @@ type Server struct {
 	logger         *zap.Logger
 	db             fdb.Database
 	importSessions sync.Map
+	importWatches  sync.Map // same sessions, never popped, until their watch or the session ends
 	exportSessions sync.Map
+	exportWatches  sync.Map // same sessions, never popped, until their watch or the session ends
 	bindPort       int
 	certFile       string
 	keyFile        string
+	encryptionKey  *format.Key // nil if none is configured
+	redactSalt     []byte      // for redaction rules that hash or tokenize, nil if none
 
 	// comment-out line below (temporarily) to
 	// see what methods the interface doesn't
@@ -42,13 +47,15 @@ type Server struct {
 	ferry.UnimplementedFerryServer
 }
 
-func NewServer(db fdb.Database, bindPort int, certFile, keyFile string, logger *zap.Logger) *Server {
+func NewServer(db fdb.Database, bindPort int, certFile, keyFile string, encryptionKey *format.Key, redactSalt []byte, logger *zap.Logger) *Server {
 	return &Server{
-		logger:   logger,
-		db:       db,
-		bindPort: bindPort,
-		certFile: certFile,
-		keyFile:  keyFile,
+		logger:        logger,
+		db:            db,
+		bindPort:      bindPort,
+		certFile:      certFile,
+		keyFile:       keyFile,
+		encryptionKey: encryptionKey,
+		redactSalt:    redactSalt,
 	}
 }
 